@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var blueskyIconURL = "https://bsky.app/static/favicon-32x32.png"
+
+type BlueskyPost struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	PostURI  string `gorm:"not null" form:"post_uri" json:"post_uri"`
+	Language string `form:"language" json:"language"`
+}
+
+type BlueskySessionResponse struct {
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	Handle     string `json:"handle"`
+	Did        string `json:"did"`
+}
+
+type BlueskySearchResponse struct {
+	Cursor    string              `json:"cursor"`
+	HitsTotal int                 `json:"hitsTotal"`
+	Posts     []BlueskyPostResult `json:"posts"`
+}
+
+type BlueskyPostResult struct {
+	URI    string `json:"uri"`
+	Cid    string `json:"cid"`
+	Author struct {
+		Did         string    `json:"did"`
+		Handle      string    `json:"handle"`
+		DisplayName string    `json:"displayName"`
+		Avatar      string    `json:"avatar"`
+		CreatedAt   time.Time `json:"createdAt"`
+	} `json:"author"`
+	Record struct {
+		Type      string    `json:"$type"`
+		Text      string    `json:"text"`
+		CreatedAt time.Time `json:"createdAt"`
+		Langs     []string  `json:"langs"`
+		Reply     *struct {
+			Parent struct {
+				URI string `json:"uri"`
+				Cid string `json:"cid"`
+			} `json:"parent"`
+			Root struct {
+				URI string `json:"uri"`
+				Cid string `json:"cid"`
+			} `json:"root"`
+		} `json:"reply"`
+		Embed *struct {
+			External *struct {
+				URI string `json:"uri"`
+			} `json:"external"`
+		} `json:"embed"`
+		Facets []struct {
+			Features []struct {
+				URI string `json:"uri"`
+			} `json:"features"`
+		} `json:"facets"`
+	} `json:"record"`
+	ReplyCount  int       `json:"replyCount"`
+	RepostCount int       `json:"repostCount"`
+	LikeCount   int       `json:"likeCount"`
+	QuoteCount  int       `json:"quoteCount"`
+	IndexedAt   time.Time `json:"indexedAt"`
+}
+
+// Link returns the bsky.app url for the post, which is keyed by the
+// record key at the end of the at:// uri
+func (p BlueskyPostResult) Link() string {
+	parts := strings.Split(p.URI, "/")
+	return fmt.Sprintf("https://bsky.app/profile/%s/post/%s", p.Author.Handle, parts[len(parts)-1])
+}
+
+func getBlueskyAccessToken(config *Config) (string, error) {
+	var response BlueskySessionResponse
+	client := resty.New()
+	resp, err := client.R().
+		SetBody(map[string]string{
+			"identifier": config.BlueskyIdentifier,
+			"password":   config.BlueskyAppPassword,
+		}).
+		SetResult(&response).
+		Post("https://bsky.social/xrpc/com.atproto.server.createSession")
+	if err != nil {
+		return "", err
+	}
+
+	if resp.IsError() {
+		return "", fmt.Errorf("error creating bluesky session: %s", resp.Status())
+	}
+
+	return response.AccessJwt, nil
+}
+
+func getBlueskyPosts(config *Config) ([]BlueskyPostResult, error) {
+	var results []BlueskyPostResult
+
+	// the public appview allows unauthenticated search, but is more
+	// aggressively rate-limited, so prefer a session when one is configured
+	host := "https://public.api.bsky.app"
+	token := ""
+	if config.BlueskyIdentifier != "" && config.BlueskyAppPassword != "" {
+		var err error
+		token, err = getBlueskyAccessToken(config)
+		if err != nil {
+			return results, err
+		}
+		host = "https://bsky.social"
+	}
+
+	cursor := ""
+	for {
+		log.WithField("cursor", cursor).Info("Fetching page")
+		var response BlueskySearchResponse
+		client := resty.New()
+		request := client.R().
+			SetQueryParams(map[string]string{
+				"q":     config.Tag,
+				"sort":  "latest",
+				"limit": "100",
+				"since": time.Now().AddDate(0, 0, -1).UTC().Format(time.RFC3339),
+			}).
+			SetResult(&response)
+		if cursor != "" {
+			request.SetQueryParam("cursor", cursor)
+		}
+		if token != "" {
+			request.SetAuthToken(token)
+		}
+
+		resp, err := request.Get(fmt.Sprintf("%s/xrpc/app.bsky.feed.searchPosts", host))
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching bluesky posts: %s", resp.Status())
+		}
+
+		for _, post := range response.Posts {
+			if !config.Matcher.Verify("bluesky", post.URI, post.Record.Text) {
+				continue
+			}
+
+			if !isRelevantBlueskyPost(post, config) {
+				continue
+			}
+
+			results = append(results, post)
+		}
+
+		if response.Cursor == "" || len(response.Posts) == 0 {
+			break
+		}
+		cursor = response.Cursor
+	}
+
+	return results, nil
+}
+
+// FilterItem converts the post for evaluation against the filter rules,
+// where the links include any link card and links within the text
+func (p BlueskyPostResult) FilterItem() FilterItem {
+	reported := ""
+	if len(p.Record.Langs) > 0 {
+		reported = p.Record.Langs[0]
+	}
+
+	item := FilterItem{
+		Source:   "bluesky",
+		Text:     p.Record.Text,
+		Author:   strings.TrimSuffix(p.Author.Handle, ".bsky.social"),
+		Language: itemLanguage(reported, p.Record.Text),
+		URL:      p.Link(),
+		Links:    []string{p.Link()},
+		Metrics: map[string]float64{
+			"likes":   float64(p.LikeCount),
+			"replies": float64(p.ReplyCount),
+			"reposts": float64(p.RepostCount),
+		},
+	}
+
+	if p.Record.Embed != nil && p.Record.Embed.External != nil {
+		item.Links = append(item.Links, p.Record.Embed.External.URI)
+	}
+	for _, facet := range p.Record.Facets {
+		for _, feature := range facet.Features {
+			item.Links = append(item.Links, feature.URI)
+		}
+	}
+
+	return item
+}
+
+// isRelevantBlueskyPost applies the filter rules, ignoring accounts named
+// after the tag unless a rule explicitly allows the post
+func isRelevantBlueskyPost(post BlueskyPostResult, config *Config) bool {
+	decision := config.Filters.Evaluate(post.FilterItem())
+	if decision.Rule != nil {
+		return decision.Allowed
+	}
+
+	// ignore anyone with the tag in the handle
+	if strings.Contains(strings.ToLower(post.Author.Handle), config.Tag) {
+		return false
+	}
+
+	// ignore anyone with the tag in the display name
+	if strings.Contains(strings.ToLower(post.Author.DisplayName), config.Tag) {
+		return false
+	}
+
+	return true
+}
+
+func sendSlackNotificationForBlueskyPost(result BlueskyPostResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"post_uri": result.URI,
+	}
+
+	link := result.Link()
+	authorName := result.Author.Handle
+	if result.Author.DisplayName != "" {
+		authorName = fmt.Sprintf("%s (@%s)", result.Author.DisplayName, result.Author.Handle)
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "# Likes",
+			Value: strconv.FormatInt(int64(result.LikeCount), 10),
+			Short: true,
+		},
+		{
+			Title: "# Reposts",
+			Value: strconv.FormatInt(int64(result.RepostCount), 10),
+			Short: true,
+		},
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New post on Bluesky!",
+		AuthorName: authorName,
+		AuthorIcon: result.Author.Avatar,
+		AuthorLink: fmt.Sprintf("https://bsky.app/profile/%s", result.Author.Handle),
+		Title:      "New post on Bluesky!",
+		TitleLink:  link,
+		Text:       result.Record.Text,
+		Footer:     "Bluesky Post Notification",
+		FooterIcon: blueskyIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.Record.CreatedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":bluesky:"),
+		slack.MsgOptionText("New post on <"+link+"|Bluesky>", false),
+		slack.MsgOptionUsername("Bluesky Post Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processBluesky(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&BlueskyPost{}); err != nil {
+		return fmt.Errorf("error migrating BlueskyPost: %w", err)
+	}
+
+	log.Info("Fetching posts")
+	results, err := getBlueskyPosts(config)
+	if err != nil {
+		return err
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("post_count", len(results)).Info("Processing posts")
+	for _, result := range results {
+		logFields := log.Fields{
+			"post_uri": result.URI,
+		}
+
+		var entity BlueskyPost
+		if dbResult := db.First(&entity, "post_uri = ?", result.URI); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new post")
+		item := result.FilterItem()
+		entity = BlueskyPost{
+			PostURI:  result.URI,
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting post into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording post")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForBlueskyPost(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting post to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for post")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_post_count": len(results),
+		"inserted_post_count":  inserted,
+		"notified_post_count":  notified,
+	}).Info("Done with bluesky posts")
+
+	return nil
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// classifierHoldoutEvery puts every nth marked mention in the held-out set
+// used to evaluate a newly trained classifier
+var classifierHoldoutEvery = 5
+
+// classifierListLimit caps how many mentions are listed for triage
+var classifierListLimit = 50
+
+var classifierRelevant = "relevant"
+var classifierIrrelevant = "irrelevant"
+
+// Mention is the text of a result from any source, recorded so that it can be
+// marked as relevant or irrelevant and used to train the classifier
+type Mention struct {
+	ID        int32     `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Source    string    `gorm:"not null" form:"source" json:"source"`
+	URL       string    `form:"url" json:"url"`
+	Author    string    `form:"author" json:"author"`
+	Language  string    `form:"language" json:"language"`
+	Text      string    `gorm:"not null" form:"text" json:"text"`
+	Score     *float64  `form:"score" json:"score"`
+	Relevant  *bool     `form:"relevant" json:"relevant"`
+	CreatedAt time.Time `form:"created_at" json:"created_at"`
+
+	// Fingerprint is the simhash of the text, and DuplicateOf the earliest
+	// mention it is a near-duplicate of
+	Fingerprint *int64 `gorm:"index" form:"fingerprint" json:"fingerprint"`
+	DuplicateOf *int32 `form:"duplicate_of" json:"duplicate_of"`
+
+	// the channel the mention was notified in, and the thread it was
+	// posted to, which near-duplicates are grouped under
+	SlackChannelID  string `form:"slack_channel_id" json:"slack_channel_id"`
+	ThreadChannelID string `form:"thread_channel_id" json:"thread_channel_id"`
+	ThreadTS        string `form:"thread_ts" json:"thread_ts"`
+}
+
+// ClassifierModel stores a trained classifier. The most recently trained
+// model is the one used to score new mentions.
+type ClassifierModel struct {
+	ID        int32     `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Model     string    `gorm:"not null" form:"model" json:"model"`
+	Documents int       `form:"documents" json:"documents"`
+	TrainedAt time.Time `form:"trained_at" json:"trained_at"`
+}
+
+// NaiveBayes is a multinomial naive bayes classifier that scores how likely a
+// mention is to be relevant
+type NaiveBayes struct {
+	Documents      map[string]int            `json:"documents"`
+	TokenCounts    map[string]map[string]int `json:"token_counts"`
+	TokenTotals    map[string]int            `json:"token_totals"`
+	VocabularySize int                       `json:"vocabulary_size"`
+}
+
+// newMention converts a filter item into a mention to be recorded
+func newMention(item FilterItem) Mention {
+	return Mention{
+		Source:      item.Source,
+		URL:         item.URL,
+		Author:      item.Author,
+		Language:    item.Language,
+		Text:        item.Text,
+		Fingerprint: mentionFingerprint(item.Text),
+	}
+}
+
+// Tokens returns the features the classifier is trained on: the words in the
+// text, along with the source, author, language and linked domain, which are
+// often more telling than the text itself
+func (m Mention) Tokens() []string {
+	tokens := strings.FieldsFunc(strings.ToLower(m.Text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	features := []string{"source:" + m.Source}
+	for _, token := range tokens {
+		if len([]rune(token)) > 1 {
+			features = append(features, token)
+		}
+	}
+
+	if m.Author != "" {
+		features = append(features, "author:"+strings.ToLower(m.Author))
+	}
+	if m.Language != "" {
+		features = append(features, "language:"+m.Language)
+	}
+	if u, err := url.Parse(m.URL); err == nil && u.Hostname() != "" {
+		features = append(features, "domain:"+strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."))
+	}
+
+	return features
+}
+
+// trainNaiveBayes trains a classifier on mentions that have been marked
+func trainNaiveBayes(mentions []Mention) *NaiveBayes {
+	model := &NaiveBayes{
+		Documents: map[string]int{},
+		TokenCounts: map[string]map[string]int{
+			classifierRelevant:   {},
+			classifierIrrelevant: {},
+		},
+		TokenTotals: map[string]int{},
+	}
+
+	vocabulary := map[string]bool{}
+	for _, mention := range mentions {
+		if mention.Relevant == nil {
+			continue
+		}
+
+		class := classifierIrrelevant
+		if *mention.Relevant {
+			class = classifierRelevant
+		}
+
+		model.Documents[class] += 1
+		for _, token := range mention.Tokens() {
+			model.TokenCounts[class][token] += 1
+			model.TokenTotals[class] += 1
+			vocabulary[token] = true
+		}
+	}
+
+	model.VocabularySize = len(vocabulary)
+	return model
+}
+
+// Score returns the probability that the mention is relevant, using laplace
+// smoothing for tokens that were not seen in training
+func (m *NaiveBayes) Score(mention Mention) float64 {
+	total := float64(m.Documents[classifierRelevant] + m.Documents[classifierIrrelevant])
+	logProbability := map[string]float64{}
+	for _, class := range []string{classifierRelevant, classifierIrrelevant} {
+		logProbability[class] = math.Log((float64(m.Documents[class]) + 1) / (total + 2))
+		denominator := float64(m.TokenTotals[class] + m.VocabularySize + 1)
+		for _, token := range mention.Tokens() {
+			logProbability[class] += math.Log((float64(m.TokenCounts[class][token]) + 1) / denominator)
+		}
+	}
+
+	return 1 / (1 + math.Exp(logProbability[classifierIrrelevant]-logProbability[classifierRelevant]))
+}
+
+// loadClassifier returns the most recently trained classifier, or nil if one
+// has not been trained yet
+func loadClassifier(db *gorm.DB) (*NaiveBayes, error) {
+	if err := db.AutoMigrate(&Mention{}, &ClassifierModel{}); err != nil {
+		return nil, fmt.Errorf("error migrating Mention: %w", err)
+	}
+
+	var entity ClassifierModel
+	if dbResult := db.Order("id desc").First(&entity); dbResult.Error != nil {
+		if errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error fetching classifier: %w", dbResult.Error)
+	}
+
+	var model NaiveBayes
+	if err := json.Unmarshal([]byte(entity.Model), &model); err != nil {
+		return nil, fmt.Errorf("error decoding classifier: %w", err)
+	}
+
+	return &model, nil
+}
+
+// prepareMention records the mention, scores it with the classifier and
+// looks for near-duplicates of it. It returns the config that notifications
+// for the mention should be sent with, which posts to the review channel
+// when the mention scores below the threshold and carries the thread the
+// notification is posted to, or nil when the notification should be
+// suppressed.
+func prepareMention(item FilterItem, config *Config, db *gorm.DB) (*Config, error) {
+	mention := newMention(item)
+	if config.Classifier != nil {
+		score := config.Classifier.Score(mention)
+		mention.Score = &score
+	}
+
+	duplicates, err := nearDuplicates(mention, config, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(duplicates) > 0 {
+		mention.DuplicateOf = &duplicates[0].ID
+		if duplicates[0].DuplicateOf != nil {
+			mention.DuplicateOf = duplicates[0].DuplicateOf
+		}
+	}
+
+	if dbResult := db.Create(&mention); dbResult.Error != nil {
+		return nil, fmt.Errorf("error inserting mention into database: %w", dbResult.Error)
+	}
+
+	if err := recordFingerprintBands(mention, db); err != nil {
+		return nil, err
+	}
+
+	logFields := log.Fields{
+		"mention_id": mention.ID,
+		"source":     mention.Source,
+	}
+
+	notifyConfig := *config
+	if mention.Score != nil && *mention.Score < config.ClassifierThreshold {
+		logFields["score"] = *mention.Score
+		if config.ClassifierChannelID == "" {
+			log.WithFields(logFields).Info("Low relevance score, suppressing notification")
+			return nil, nil
+		}
+
+		log.WithFields(logFields).Info("Low relevance score, notifying review channel")
+		notifyConfig.SlackChannelID = config.ClassifierChannelID
+	}
+
+	if mention.DuplicateOf != nil {
+		logFields["duplicate_of"] = *mention.DuplicateOf
+		if config.DuplicateAction == "suppress" {
+			log.WithFields(logFields).Info("Near-duplicate of an earlier mention, suppressing notification")
+			return nil, nil
+		}
+	}
+
+	thread := &SlackThread{
+		mentionID: mention.ID,
+		source:    item.Source,
+	}
+
+	// group the mention under the thread of the earliest near-duplicate
+	// that was notified in the same channel
+	for _, duplicate := range duplicates {
+		if duplicate.ThreadTS != "" && duplicate.SlackChannelID == notifyConfig.SlackChannelID {
+			log.WithFields(logFields).Info("Near-duplicate of an earlier mention, replying in thread")
+			thread.ChannelID = duplicate.ThreadChannelID
+			thread.TS = duplicate.ThreadTS
+			break
+		}
+	}
+
+	if err := threadLinks(thread, item, &notifyConfig, db); err != nil {
+		return nil, err
+	}
+
+	notifyConfig.SlackThread = thread
+	return &notifyConfig, nil
+}
+
+// recordNotification records the thread a mention was posted to once its
+// notification has been sent, so that near-duplicates and later sightings
+// of the links it shares can be grouped under it
+func recordNotification(config *Config, db *gorm.DB) error {
+	thread := config.SlackThread
+	if thread == nil {
+		return nil
+	}
+
+	// nothing is posted when slack notifications are disabled
+	if thread.TS != "" {
+		dbResult := db.Model(&Mention{}).Where("id = ?", thread.mentionID).Updates(map[string]interface{}{
+			"slack_channel_id":  config.SlackChannelID,
+			"thread_channel_id": thread.ChannelID,
+			"thread_ts":         thread.TS,
+		})
+		if dbResult.Error != nil {
+			return fmt.Errorf("error updating mention %d: %w", thread.mentionID, dbResult.Error)
+		}
+	}
+
+	return recordLinks(config, db)
+}
+
+// listMentions prints the most recent mentions that have not been marked, so
+// that they can be triaged
+func listMentions(db *gorm.DB) error {
+	var mentions []Mention
+	if dbResult := db.Where("relevant IS NULL").Order("id desc").Limit(classifierListLimit).Find(&mentions); dbResult.Error != nil {
+		return fmt.Errorf("error fetching mentions: %w", dbResult.Error)
+	}
+
+	for _, mention := range mentions {
+		score := "-"
+		if mention.Score != nil {
+			score = strconv.FormatFloat(*mention.Score, 'f', 2, 64)
+		}
+
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\n", mention.ID, score, mention.Source, mention.URL, textExcerpt(mention.Text, 80))
+	}
+
+	return nil
+}
+
+// markMentions marks the comma-separated mention ids as relevant or not
+func markMentions(db *gorm.DB, ids string, relevant bool) error {
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+
+		dbResult := db.Model(&Mention{}).Where("id = ?", id).Update("relevant", relevant)
+		if dbResult.Error != nil {
+			return fmt.Errorf("error marking mention %s: %w", id, dbResult.Error)
+		}
+		if dbResult.RowsAffected == 0 {
+			return fmt.Errorf("mention %s not found", id)
+		}
+
+		log.WithFields(log.Fields{
+			"mention_id": id,
+			"relevant":   relevant,
+		}).Info("Marked mention")
+	}
+
+	return nil
+}
+
+// trainClassifier evaluates a classifier trained without the held-out
+// mentions, printing its precision and recall on them, and then saves a
+// classifier trained on every marked mention
+func trainClassifier(config *Config, db *gorm.DB) error {
+	var mentions []Mention
+	if dbResult := db.Where("relevant IS NOT NULL").Order("id").Find(&mentions); dbResult.Error != nil {
+		return fmt.Errorf("error fetching marked mentions: %w", dbResult.Error)
+	}
+
+	var training []Mention
+	var heldOut []Mention
+	for i, mention := range mentions {
+		if i%classifierHoldoutEvery == classifierHoldoutEvery-1 {
+			heldOut = append(heldOut, mention)
+		} else {
+			training = append(training, mention)
+		}
+	}
+
+	model := trainNaiveBayes(training)
+	if model.Documents[classifierRelevant] == 0 || model.Documents[classifierIrrelevant] == 0 {
+		return fmt.Errorf("at least one relevant and one irrelevant mention must be marked to train the classifier")
+	}
+
+	truePositives, falsePositives, trueNegatives, falseNegatives := 0, 0, 0, 0
+	for _, mention := range heldOut {
+		predicted := model.Score(mention) >= config.ClassifierThreshold
+		switch {
+		case predicted && *mention.Relevant:
+			truePositives += 1
+		case predicted && !*mention.Relevant:
+			falsePositives += 1
+		case !predicted && !*mention.Relevant:
+			trueNegatives += 1
+		default:
+			falseNegatives += 1
+		}
+	}
+
+	fmt.Printf("Trained on %d mentions, evaluated on %d held-out mentions at a threshold of %.2f\n", len(training), len(heldOut), config.ClassifierThreshold)
+	fmt.Printf("relevant:   precision %s, recall %s\n", classifierRatio(truePositives, truePositives+falsePositives), classifierRatio(truePositives, truePositives+falseNegatives))
+	fmt.Printf("irrelevant: precision %s, recall %s\n", classifierRatio(trueNegatives, trueNegatives+falseNegatives), classifierRatio(trueNegatives, trueNegatives+falsePositives))
+
+	model = trainNaiveBayes(mentions)
+	data, err := json.Marshal(model)
+	if err != nil {
+		return fmt.Errorf("error encoding classifier: %w", err)
+	}
+
+	entity := ClassifierModel{
+		Model:     string(data),
+		Documents: len(mentions),
+		TrainedAt: time.Now(),
+	}
+	if dbResult := db.Create(&entity); dbResult.Error != nil {
+		return fmt.Errorf("error inserting classifier into database: %w", dbResult.Error)
+	}
+
+	fmt.Printf("Saved classifier trained on all %d marked mentions\n", len(mentions))
+	return nil
+}
+
+// classifierRatio formats a ratio, which is undefined when there is nothing
+// to divide by
+func classifierRatio(numerator int, denominator int) string {
+	if denominator == 0 {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%.2f (%d/%d)", float64(numerator)/float64(denominator), numerator, denominator)
+}
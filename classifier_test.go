@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func boolPointer(v bool) *bool {
+	return &v
+}
+
+func TestMentionTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		mention Mention
+		want    []string
+	}{
+		{
+			name:    "text is lowercased and split on punctuation",
+			mention: Mention{Source: "reddit", Text: "Deploying with Dokku: git-push!"},
+			want:    []string{"source:reddit", "deploying", "with", "dokku", "git", "push"},
+		},
+		{
+			name:    "single characters are dropped",
+			mention: Mention{Source: "reddit", Text: "a b dokku"},
+			want:    []string{"source:reddit", "dokku"},
+		},
+		{
+			name: "author, language and domain",
+			mention: Mention{
+				Source:   "hackernews_story",
+				Text:     "dokku",
+				Author:   "JoseGonzalez",
+				Language: "en",
+				URL:      "https://www.Example.com/post",
+			},
+			want: []string{"source:hackernews_story", "dokku", "author:josegonzalez", "language:en", "domain:example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mention.Tokens(); !slices.Equal(got, tt.want) {
+				t.Errorf("Tokens() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrainNaiveBayes(t *testing.T) {
+	model := trainNaiveBayes([]Mention{
+		{Source: "reddit", Text: "dokku deploy", Relevant: boolPointer(true)},
+		{Source: "reddit", Text: "dokku server", Relevant: boolPointer(true)},
+		{Source: "twitter", Text: "count dooku", Relevant: boolPointer(false)},
+		{Source: "twitter", Text: "unmarked mention"},
+	})
+
+	tests := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{name: "relevant documents", got: model.Documents[classifierRelevant], want: 2},
+		{name: "irrelevant documents", got: model.Documents[classifierIrrelevant], want: 1},
+		{name: "relevant token count", got: model.TokenCounts[classifierRelevant]["dokku"], want: 2},
+		{name: "irrelevant token count", got: model.TokenCounts[classifierIrrelevant]["dokku"], want: 0},
+		{name: "relevant token total", got: model.TokenTotals[classifierRelevant], want: 6},
+		{name: "irrelevant token total", got: model.TokenTotals[classifierIrrelevant], want: 3},
+		{name: "vocabulary excludes unmarked mentions", got: model.VocabularySize, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %d, want %d", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNaiveBayesScore(t *testing.T) {
+	model := trainNaiveBayes([]Mention{
+		{Source: "reddit", Text: "deploying my app with dokku on a vps", Relevant: boolPointer(true)},
+		{Source: "reddit", Text: "dokku plugin for postgres backups", Relevant: boolPointer(true)},
+		{Source: "hackernews_story", Text: "switched from heroku to dokku", Relevant: boolPointer(true)},
+		{Source: "twitter", Text: "count dooku is the best star wars villain", Relevant: boolPointer(false)},
+		{Source: "twitter", Text: "dokkan battle summon results", Relevant: boolPointer(false)},
+	})
+
+	tests := []struct {
+		name    string
+		mention Mention
+		wantMin float64
+		wantMax float64
+	}{
+		{
+			name:    "relevant",
+			mention: Mention{Source: "reddit", Text: "dokku backups for postgres"},
+			wantMin: 0.9,
+			wantMax: 1,
+		},
+		{
+			name:    "irrelevant",
+			mention: Mention{Source: "twitter", Text: "star wars villain count dooku"},
+			wantMin: 0,
+			wantMax: 0.1,
+		},
+		{
+			name:    "unseen tokens are undecided",
+			mention: Mention{Source: "lemmy", Text: "zzz qqq"},
+			wantMin: 0.3,
+			wantMax: 0.7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := model.Score(tt.mention)
+			if math.IsNaN(score) || score < tt.wantMin || score > tt.wantMax {
+				t.Errorf("Score() = %v, want between %v and %v", score, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestNaiveBayesScoreUntrained(t *testing.T) {
+	model := trainNaiveBayes(nil)
+	if score := model.Score(Mention{Source: "reddit", Text: "dokku"}); score != 0.5 {
+		t.Errorf("Score() = %v, want 0.5", score)
+	}
+}
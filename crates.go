@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var cratesRegistry = Registry{
+	Service:   "crates",
+	Name:      "crates.io",
+	IconURL:   "https://crates.io/favicon.ico",
+	IconEmoji: ":rust:",
+}
+
+// cratesUserAgent identifies the service, as crates.io rejects requests
+// without a descriptive user agent
+var cratesUserAgent = "social-notifications (+https://github.com/dokku/social-notifications)"
+
+// cratesMaxPages caps how many pages are fetched on a single run
+var cratesMaxPages = 5
+
+type CratesSearchResponse struct {
+	Crates []CratesItem `json:"crates"`
+	Meta   struct {
+		Total int `json:"total"`
+	} `json:"meta"`
+}
+
+type CratesItem struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	MaxVersion    string    `json:"max_version"`
+	NewestVersion string    `json:"newest_version"`
+	Downloads     int       `json:"downloads"`
+	Repository    string    `json:"repository"`
+	Homepage      string    `json:"homepage"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func getCratesPackages(config *Config) ([]PackageResult, error) {
+	var results []PackageResult
+	for page := 1; page <= cratesMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		var response CratesSearchResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetHeader("User-Agent", cratesUserAgent).
+			SetQueryParams(map[string]string{
+				"q":        config.Tag,
+				"per_page": "100",
+				"page":     strconv.FormatInt(int64(page), 10),
+				"sort":     "new",
+			}).
+			SetResult(&response).
+			Get("https://crates.io/api/v1/crates")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching crates: %s", resp.Status())
+		}
+
+		if len(response.Crates) == 0 {
+			break
+		}
+
+		for _, crate := range response.Crates {
+			if !config.Matcher.Verify("crates", crate.ID, crate.Name, crate.Description) {
+				continue
+			}
+
+			version := crate.NewestVersion
+			if version == "" {
+				version = crate.MaxVersion
+			}
+
+			results = append(results, PackageResult{
+				Registry:    cratesRegistry,
+				Name:        crate.Name,
+				Version:     version,
+				Description: crate.Description,
+				URL:         fmt.Sprintf("https://crates.io/crates/%s", crate.ID),
+				Downloads:   crate.Downloads,
+				PublishedAt: crate.UpdatedAt,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func processCratesPackages(config *Config, db *gorm.DB) error {
+	log.Info("Fetching packages")
+	results, err := getCratesPackages(config)
+	if err != nil {
+		return err
+	}
+
+	return processRegistryPackages(results, config, db)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -19,6 +20,7 @@ type DevtoArticle struct {
 	ID        int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
 	ArticleID int64  `gorm:"not null" form:"article_id" json:"article_id"`
 	Title     string `gorm:"not null" form:"title" json:"title"`
+	Language  string `form:"language" json:"language"`
 }
 
 type DevtoResponse []DevtoArticleResult
@@ -72,6 +74,23 @@ type DevtoArticleResult struct {
 	} `json:"flare_tag"`
 }
 
+// FilterItem converts the article for evaluation against the filter rules
+func (r DevtoArticleResult) FilterItem() FilterItem {
+	text := strings.Join([]string{r.Title, r.Description}, " ")
+	return FilterItem{
+		Source:   "devto",
+		Text:     text,
+		Author:   r.User.Username,
+		Language: detectLanguage(text),
+		URL:      r.URL,
+		Links:    []string{r.URL, r.CanonicalURL},
+		Metrics: map[string]float64{
+			"comments":  float64(r.CommentsCount),
+			"reactions": float64(r.PublicReactionsCount),
+		},
+	}
+}
+
 func getDevtoArticles(config *Config) ([]DevtoArticleResult, error) {
 	var results []DevtoArticleResult
 	page := 1
@@ -96,7 +115,14 @@ func getDevtoArticles(config *Config) ([]DevtoArticleResult, error) {
 			break
 		}
 
-		results = append(results, response...)
+		for _, article := range response {
+			values := append([]string{article.Title, article.Description}, article.TagList...)
+			if !config.Matcher.Verify("devto", strconv.FormatInt(int64(article.ID), 10), values...) {
+				continue
+			}
+
+			results = append(results, article)
+		}
 	}
 
 	return results, nil
@@ -134,12 +160,7 @@ func sendSlackNotificationForDevtoArticle(result DevtoArticleResult, config *Con
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
-	}
-
-	return nil
+	return postSlackMessage(config, messageOpts...)
 }
 
 func processDevtoArticles(config *Config, db *gorm.DB) error {
@@ -162,6 +183,11 @@ func processDevtoArticles(config *Config, db *gorm.DB) error {
 			"title":      result.Title,
 		}
 
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
 		var entity DevtoArticle
 		if dbResult := db.First(&entity, "article_id = ?", result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
 			continue
@@ -171,6 +197,7 @@ func processDevtoArticles(config *Config, db *gorm.DB) error {
 		entity = DevtoArticle{
 			ArticleID: int64(result.ID),
 			Title:     result.Title,
+			Language:  item.Language,
 		}
 
 		if dbResult := db.Create(&entity); dbResult.Error != nil {
@@ -179,11 +206,25 @@ func processDevtoArticles(config *Config, db *gorm.DB) error {
 		}
 
 		inserted += 1
-		if err := sendSlackNotificationForDevtoArticle(result, config); err != nil {
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording article")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForDevtoArticle(result, notifyConfig); err != nil {
 			log.WithError(err).WithFields(logFields).Fatal("error posting article to slack")
 			continue
 		}
 
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for article")
+			continue
+		}
+
 		notified += 1
 	}
 	log.WithFields(log.Fields{
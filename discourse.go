@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var discourseIconURL = "https://www.discourse.org/a/img/favicon.png"
+
+// discourseMaxPages caps how many search pages are fetched per forum on a
+// single run
+var discourseMaxPages = 5
+
+type DiscoursePost struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Forum    string `gorm:"not null" form:"forum" json:"forum"`
+	TopicID  int64  `gorm:"not null" form:"topic_id" json:"topic_id"`
+	PostID   int64  `gorm:"not null" form:"post_id" json:"post_id"`
+	Title    string `gorm:"not null" form:"title" json:"title"`
+	Language string `form:"language" json:"language"`
+}
+
+type DiscourseSearchResponse struct {
+	Posts               []DiscoursePostResult  `json:"posts"`
+	Topics              []DiscourseTopicResult `json:"topics"`
+	GroupedSearchResult struct {
+		MorePosts           bool   `json:"more_posts"`
+		MoreFullPageResults bool   `json:"more_full_page_results"`
+		Term                string `json:"term"`
+		SearchLogID         int    `json:"search_log_id"`
+		CanCreateTopic      bool   `json:"can_create_topic"`
+		PostIDs             []int  `json:"post_ids"`
+		TopicIDs            []int  `json:"topic_ids"`
+		CategoryIDs         []int  `json:"category_ids"`
+		UserIDs             []int  `json:"user_ids"`
+		MoreCategories      bool   `json:"more_categories"`
+		MoreUsers           bool   `json:"more_users"`
+	} `json:"grouped_search_result"`
+}
+
+type DiscoursePostResult struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Username       string    `json:"username"`
+	AvatarTemplate string    `json:"avatar_template"`
+	CreatedAt      time.Time `json:"created_at"`
+	LikeCount      int       `json:"like_count"`
+	Blurb          string    `json:"blurb"`
+	PostNumber     int       `json:"post_number"`
+	TopicID        int       `json:"topic_id"`
+}
+
+type DiscourseTopicResult struct {
+	ID           int       `json:"id"`
+	Title        string    `json:"title"`
+	FancyTitle   string    `json:"fancy_title"`
+	Slug         string    `json:"slug"`
+	PostsCount   int       `json:"posts_count"`
+	ReplyCount   int       `json:"reply_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastPostedAt time.Time `json:"last_posted_at"`
+	Closed       bool      `json:"closed"`
+	Archived     bool      `json:"archived"`
+	CategoryID   int       `json:"category_id"`
+	Tags         []string  `json:"tags"`
+}
+
+type DiscourseSiteResponse struct {
+	Categories []struct {
+		ID               int    `json:"id"`
+		Name             string `json:"name"`
+		Slug             string `json:"slug"`
+		ParentCategoryID int    `json:"parent_category_id"`
+	} `json:"categories"`
+}
+
+// DiscourseResult is a matching post along with the topic and category it
+// belongs to
+type DiscourseResult struct {
+	Forum    string
+	Post     DiscoursePostResult
+	Topic    DiscourseTopicResult
+	Category string
+}
+
+// Link returns the url to the post within its topic
+func (r DiscourseResult) Link() string {
+	return fmt.Sprintf("%s/t/%s/%d/%d", instanceURL(r.Forum), r.Topic.Slug, r.Topic.ID, r.Post.PostNumber)
+}
+
+// AvatarURL expands the post author's avatar template into a full url
+func (r DiscourseResult) AvatarURL() string {
+	avatar := strings.ReplaceAll(r.Post.AvatarTemplate, "{size}", "90")
+	if strings.HasPrefix(avatar, "/") && !strings.HasPrefix(avatar, "//") {
+		avatar = instanceURL(r.Forum) + avatar
+	}
+
+	return avatar
+}
+
+// FilterItem converts the post for evaluation against the filter rules
+func (r DiscourseResult) FilterItem() FilterItem {
+	text := strings.Join([]string{r.Topic.Title, r.Post.Blurb}, " ")
+	return FilterItem{
+		Source:   "discourse",
+		Text:     text,
+		Author:   r.Post.Username,
+		Language: detectLanguage(text),
+		URL:      r.Link(),
+		Links:    []string{r.Link()},
+		Metrics: map[string]float64{
+			"likes":   float64(r.Post.LikeCount),
+			"replies": float64(r.Topic.ReplyCount),
+		},
+	}
+}
+
+func getDiscourseCategories(forum string) (map[int]string, error) {
+	categories := map[int]string{}
+	var response DiscourseSiteResponse
+	client := resty.New()
+	resp, err := client.R().
+		SetResult(&response).
+		Get(fmt.Sprintf("%s/site.json", instanceURL(forum)))
+	if err != nil {
+		return categories, err
+	}
+
+	if resp.IsError() {
+		return categories, fmt.Errorf("error fetching categories from %s: %s", instanceHost(forum), resp.Status())
+	}
+
+	for _, category := range response.Categories {
+		categories[category.ID] = category.Name
+	}
+
+	return categories, nil
+}
+
+func getDiscoursePosts(forum string, config *Config) ([]DiscourseResult, error) {
+	var results []DiscourseResult
+	categories, err := getDiscourseCategories(forum)
+	if err != nil {
+		return results, err
+	}
+
+	for page := 1; page <= discourseMaxPages; page++ {
+		log.WithFields(log.Fields{
+			"forum": instanceHost(forum),
+			"page":  page,
+		}).Info("Fetching page")
+
+		var response DiscourseSearchResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"q":    fmt.Sprintf("%s order:latest", config.Tag),
+				"page": strconv.FormatInt(int64(page), 10),
+			}).
+			SetResult(&response).
+			Get(fmt.Sprintf("%s/search.json", instanceURL(forum)))
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching %s: %s", instanceHost(forum), resp.Status())
+		}
+
+		topics := map[int]DiscourseTopicResult{}
+		for _, topic := range response.Topics {
+			topics[topic.ID] = topic
+		}
+
+		for _, post := range response.Posts {
+			topic := topics[post.TopicID]
+			if !config.Matcher.Verify("discourse", strconv.FormatInt(int64(post.ID), 10), topic.Title, post.Blurb) {
+				continue
+			}
+
+			results = append(results, DiscourseResult{
+				Forum:    instanceHost(forum),
+				Post:     post,
+				Topic:    topic,
+				Category: categories[topic.CategoryID],
+			})
+		}
+
+		if len(response.Posts) == 0 || !response.GroupedSearchResult.MoreFullPageResults {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForDiscoursePost(result DiscourseResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"forum":    result.Forum,
+		"topic_id": result.Topic.ID,
+		"post_id":  result.Post.ID,
+		"title":    result.Topic.Title,
+	}
+
+	link := result.Link()
+	kind := "post"
+	if result.Post.PostNumber == 1 {
+		kind = "topic"
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Forum",
+			Value: result.Forum,
+			Short: true,
+		},
+		{
+			Title: "# Replies",
+			Value: strconv.FormatInt(int64(result.Topic.ReplyCount), 10),
+			Short: true,
+		},
+	}
+
+	if len(result.Category) > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Category",
+			Value: result.Category,
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   fmt.Sprintf("New %s on Discourse!", kind),
+		AuthorName: result.Post.Username,
+		AuthorIcon: result.AvatarURL(),
+		AuthorLink: fmt.Sprintf("%s/u/%s", instanceURL(result.Forum), result.Post.Username),
+		Title:      result.Topic.Title,
+		TitleLink:  link,
+		Text:       result.Post.Blurb,
+		Footer:     "Discourse Notification",
+		FooterIcon: discourseIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.Post.CreatedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":discourse:"),
+		slack.MsgOptionText(fmt.Sprintf("New %s on <%s|%s>", kind, link, result.Forum), false),
+		slack.MsgOptionUsername("Discourse Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processDiscourse(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&DiscoursePost{}); err != nil {
+		return fmt.Errorf("error migrating DiscoursePost: %w", err)
+	}
+
+	if len(config.DiscourseForums) == 0 {
+		log.Warn("No DISCOURSE_FORUMS specified, skipping discourse")
+		return nil
+	}
+
+	var results []DiscourseResult
+	for _, forum := range config.DiscourseForums {
+		log.WithField("forum", instanceHost(forum)).Info("Fetching posts")
+		forumResults, err := getDiscoursePosts(forum, config)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, forumResults...)
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("post_count", len(results)).Info("Processing posts")
+	for _, result := range results {
+		logFields := log.Fields{
+			"forum":    result.Forum,
+			"topic_id": result.Topic.ID,
+			"post_id":  result.Post.ID,
+			"title":    result.Topic.Title,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity DiscoursePost
+		if dbResult := db.First(&entity, "forum = ? AND post_id = ?", result.Forum, result.Post.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new post")
+		entity = DiscoursePost{
+			Forum:    result.Forum,
+			TopicID:  int64(result.Topic.ID),
+			PostID:   int64(result.Post.ID),
+			Title:    result.Topic.Title,
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting post into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording post")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForDiscoursePost(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting post to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for post")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_post_count": len(results),
+		"inserted_post_count":  inserted,
+		"notified_post_count":  notified,
+	}).Info("Done with discourse posts")
+
+	return nil
+}
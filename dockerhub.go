@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var dockerhubRegistry = Registry{
+	Service:   "dockerhub",
+	Name:      "Docker Hub",
+	IconURL:   "https://hub.docker.com/favicon.ico",
+	IconEmoji: ":docker:",
+}
+
+// dockerhubMaxPages caps how many pages are fetched on a single run
+var dockerhubMaxPages = 5
+
+type DockerhubSearchResponse struct {
+	Count   int             `json:"count"`
+	Next    string          `json:"next"`
+	Results []DockerhubItem `json:"results"`
+}
+
+type DockerhubItem struct {
+	RepoName         string `json:"repo_name"`
+	ShortDescription string `json:"short_description"`
+	StarCount        int    `json:"star_count"`
+	PullCount        int    `json:"pull_count"`
+	RepoOwner        string `json:"repo_owner"`
+	IsOfficial       bool   `json:"is_official"`
+}
+
+type DockerhubTagsResponse struct {
+	Results []struct {
+		Name        string    `json:"name"`
+		LastUpdated time.Time `json:"last_updated"`
+	} `json:"results"`
+}
+
+// Namespace returns the owner of the repository, which is "library" for
+// official images
+func (i DockerhubItem) Namespace() string {
+	if namespace, _, ok := strings.Cut(i.RepoName, "/"); ok {
+		return namespace
+	}
+
+	return "library"
+}
+
+// Link returns the url of the repository on docker hub
+func (i DockerhubItem) Link() string {
+	if i.IsOfficial || !strings.Contains(i.RepoName, "/") {
+		return fmt.Sprintf("https://hub.docker.com/_/%s", i.RepoName)
+	}
+
+	return fmt.Sprintf("https://hub.docker.com/r/%s", i.RepoName)
+}
+
+// getDockerhubLatestTag fetches the most recently pushed tag of a
+// repository, ignoring the floating latest tag as it never changes name
+func getDockerhubLatestTag(item DockerhubItem) (string, time.Time, error) {
+	name := item.RepoName
+	if !strings.Contains(name, "/") {
+		name = fmt.Sprintf("library/%s", name)
+	}
+
+	var response DockerhubTagsResponse
+	client := resty.New()
+	resp, err := client.R().
+		SetQueryParams(map[string]string{
+			"page_size": "10",
+			"ordering":  "last_updated",
+		}).
+		SetResult(&response).
+		Get(fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags", name))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if resp.IsError() {
+		return "", time.Time{}, fmt.Errorf("error fetching docker hub tags for %s: %s", name, resp.Status())
+	}
+
+	for _, tag := range response.Results {
+		if tag.Name != "latest" {
+			return tag.Name, tag.LastUpdated, nil
+		}
+	}
+
+	return "", time.Time{}, nil
+}
+
+func getDockerhubPackages(config *Config) ([]PackageResult, error) {
+	var results []PackageResult
+	for page := 1; page <= dockerhubMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		var response DockerhubSearchResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"query":     config.Tag,
+				"page_size": "100",
+				"page":      strconv.FormatInt(int64(page), 10),
+			}).
+			SetResult(&response).
+			Get("https://hub.docker.com/v2/search/repositories/")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching docker hub repositories: %s", resp.Status())
+		}
+
+		for _, item := range response.Results {
+			if !config.Matcher.Verify("dockerhub", item.RepoName, item.RepoName, item.ShortDescription) {
+				continue
+			}
+
+			result := PackageResult{
+				Registry:    dockerhubRegistry,
+				Name:        item.RepoName,
+				Description: item.ShortDescription,
+				Author:      item.Namespace(),
+				AuthorURL:   fmt.Sprintf("https://hub.docker.com/u/%s", item.Namespace()),
+				URL:         item.Link(),
+				Downloads:   item.PullCount,
+			}
+
+			// the search results do not include tags, so only look them up
+			// when versions are being tracked
+			if config.RegistryNotifyVersions {
+				tag, lastUpdated, err := getDockerhubLatestTag(item)
+				if err != nil {
+					return results, err
+				}
+
+				result.Version = tag
+				result.PublishedAt = lastUpdated
+			}
+
+			results = append(results, result)
+		}
+
+		if response.Next == "" {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func processDockerhubPackages(config *Config, db *gorm.DB) error {
+	log.Info("Fetching packages")
+	results, err := getDockerhubPackages(config)
+	if err != nil {
+		return err
+	}
+
+	return processRegistryPackages(results, config, db)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// duplicateMinWords is the fewest words a mention must have to be
+// fingerprinted, as short posts such as "trying out dokku" are too alike to
+// tell apart from their fingerprints alone
+var duplicateMinWords = 8
+
+// duplicateBands is how many bands each fingerprint is split into. Two
+// fingerprints that differ in fewer bits than there are bands share at least
+// one band, and those differing in up to 12 bits, the most allowed by the
+// default threshold, share one over 85% of the time.
+var duplicateBands = 8
+
+// MentionBand is one band of the fingerprint of a mention, which are indexed
+// so that near-duplicate candidates are found without comparing against
+// every recent mention. Band holds the position of the band in the top bits
+// and its value in the lower ones.
+type MentionBand struct {
+	ID        int32 `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	MentionID int32 `gorm:"not null;index" form:"mention_id" json:"mention_id"`
+	Band      int32 `gorm:"not null;index" form:"band" json:"band"`
+}
+
+// migrateDuplicates creates the table fingerprint bands are indexed in, and
+// indexes the fingerprints of mentions recorded before it existed
+func migrateDuplicates(db *gorm.DB) error {
+	if err := db.AutoMigrate(&MentionBand{}); err != nil {
+		return fmt.Errorf("error migrating MentionBand: %w", err)
+	}
+
+	var mentions []Mention
+	dbResult := db.Where("fingerprint IS NOT NULL AND id NOT IN (?)", db.Model(&MentionBand{}).Select("mention_id")).Find(&mentions)
+	if dbResult.Error != nil {
+		return fmt.Errorf("error fetching mentions without bands: %w", dbResult.Error)
+	}
+
+	for _, mention := range mentions {
+		if err := recordFingerprintBands(mention, db); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// duplicateTokens normalizes the text into the words that are fingerprinted,
+// ignoring case, punctuation and links, which often carry tracking
+// parameters that differ between otherwise identical posts
+func duplicateTokens(text string) []string {
+	text = linkPattern.ReplaceAllString(strings.ToLower(text), " ")
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// simhash computes a 64 bit fingerprint of the words and adjacent pairs of
+// words. Similar texts produce fingerprints that differ in only a few bits,
+// unlike a regular hash where a single edit changes the whole value.
+func simhash(tokens []string) uint64 {
+	var weights [64]int
+	features := append([]string{}, tokens...)
+	for i := 1; i < len(tokens); i++ {
+		features = append(features, tokens[i-1]+" "+tokens[i])
+	}
+
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		value := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if value&(1<<bit) != 0 {
+				weights[bit] += 1
+			} else {
+				weights[bit] -= 1
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+
+	return fingerprint
+}
+
+// mentionFingerprint returns the simhash of the text, or nil when the text is
+// too short to be fingerprinted. The fingerprint is stored as a signed
+// integer, as sqlite has no unsigned 64 bit type.
+func mentionFingerprint(text string) *int64 {
+	tokens := duplicateTokens(text)
+	if len(tokens) < duplicateMinWords {
+		return nil
+	}
+
+	fingerprint := int64(simhash(tokens))
+	return &fingerprint
+}
+
+// fingerprintBands splits the fingerprint into bands
+func fingerprintBands(fingerprint int64) []int32 {
+	width := 64 / duplicateBands
+	var bands []int32
+	for i := 0; i < duplicateBands; i++ {
+		value := uint64(fingerprint) >> (i * width) & (1<<width - 1)
+		bands = append(bands, int32(i<<width)|int32(value))
+	}
+
+	return bands
+}
+
+// recordFingerprintBands indexes the fingerprint of a recorded mention
+func recordFingerprintBands(mention Mention, db *gorm.DB) error {
+	if mention.Fingerprint == nil {
+		return nil
+	}
+
+	var bands []MentionBand
+	for _, band := range fingerprintBands(*mention.Fingerprint) {
+		bands = append(bands, MentionBand{MentionID: mention.ID, Band: band})
+	}
+
+	if dbResult := db.Create(&bands); dbResult.Error != nil {
+		return fmt.Errorf("error inserting mention bands into database: %w", dbResult.Error)
+	}
+
+	return nil
+}
+
+// simhashSimilarity returns the fraction of bits two fingerprints share
+func simhashSimilarity(a int64, b int64) float64 {
+	return 1 - float64(bits.OnesCount64(uint64(a^b)))/64
+}
+
+// nearDuplicates returns the mentions recorded within the duplicate window
+// whose fingerprints are at least as similar as the duplicate threshold to
+// that of the mention, oldest first. Only mentions sharing a band with the
+// mention are compared.
+func nearDuplicates(mention Mention, config *Config, db *gorm.DB) ([]Mention, error) {
+	if config.DuplicateWindow <= 0 || mention.Fingerprint == nil {
+		return nil, nil
+	}
+
+	var candidates []Mention
+	since := time.Now().Add(-config.DuplicateWindow)
+	bands := db.Model(&MentionBand{}).Select("mention_id").Where("band IN ?", fingerprintBands(*mention.Fingerprint))
+	if dbResult := db.Where("id IN (?) AND created_at > ?", bands, since).Order("id").Find(&candidates); dbResult.Error != nil {
+		return nil, fmt.Errorf("error fetching recent mentions: %w", dbResult.Error)
+	}
+
+	var duplicates []Mention
+	for _, candidate := range candidates {
+		if simhashSimilarity(*mention.Fingerprint, *candidate.Fingerprint) >= config.DuplicateThreshold {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+
+	return duplicates, nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var duplicateTestPost = "Just moved all of my side projects from Heroku to a single Dokku server, and deploys are as easy as a git push"
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := CreateDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&Mention{}); err != nil {
+		t.Fatalf("error migrating Mention: %v", err)
+	}
+
+	if err := migrateDuplicates(db); err != nil {
+		t.Fatalf("error migrating duplicates: %v", err)
+	}
+
+	return db
+}
+
+func TestSimhashSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a    int64
+		b    int64
+		want float64
+	}{
+		{
+			name: "identical",
+			a:    0x0f0f0f0f0f0f0f0f,
+			b:    0x0f0f0f0f0f0f0f0f,
+			want: 1,
+		},
+		{
+			name: "one bit",
+			a:    0,
+			b:    1,
+			want: 63.0 / 64,
+		},
+		{
+			name: "sign bit",
+			a:    0,
+			b:    -1 << 63,
+			want: 63.0 / 64,
+		},
+		{
+			name: "half",
+			a:    0,
+			b:    0x00000000ffffffff,
+			want: 0.5,
+		},
+		{
+			name: "inverted",
+			a:    0,
+			b:    -1,
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := simhashSimilarity(tt.a, tt.b); got != tt.want {
+				t.Errorf("simhashSimilarity(%x, %x) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionFingerprint(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantNil     bool
+		wantSimilar bool
+	}{
+		{
+			name:    "too short",
+			text:    "trying out dokku",
+			wantNil: true,
+		},
+		{
+			name:        "identical",
+			text:        duplicateTestPost,
+			wantSimilar: true,
+		},
+		{
+			name:        "case, punctuation and links are ignored",
+			text:        "JUST moved all of my side projects from heroku to a single dokku server... and deploys are as easy as a git push! https://example.com/?utm_source=x",
+			wantSimilar: true,
+		},
+		{
+			name:        "small edit",
+			text:        "Just moved all of my side projects from Heroku to a single Dokku server, deploys are as easy as a git push now",
+			wantSimilar: true,
+		},
+		{
+			name:        "unrelated",
+			text:        "Dokku 0.34 was released today with support for multiple builders and a new scheduler for running apps on k3s",
+			wantSimilar: false,
+		},
+	}
+
+	original := mentionFingerprint(duplicateTestPost)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprint := mentionFingerprint(tt.text)
+			if tt.wantNil {
+				if fingerprint != nil {
+					t.Errorf("mentionFingerprint(%q) = %x, want nil", tt.text, *fingerprint)
+				}
+				return
+			}
+
+			if fingerprint == nil {
+				t.Fatalf("mentionFingerprint(%q) = nil", tt.text)
+			}
+
+			similarity := simhashSimilarity(*original, *fingerprint)
+			if similar := similarity >= 0.8; similar != tt.wantSimilar {
+				t.Errorf("similarity = %v, want similar %v", similarity, tt.wantSimilar)
+			}
+		})
+	}
+}
+
+func TestFingerprintBands(t *testing.T) {
+	tests := []struct {
+		name        string
+		a           int64
+		b           int64
+		wantBands   int
+		wantDiffers int
+	}{
+		{
+			name:      "identical",
+			a:         0x0123456789abcdef,
+			b:         0x0123456789abcdef,
+			wantBands: 8,
+		},
+		{
+			name:        "one band differs",
+			a:           0x0123456789abcdef,
+			b:           0x0123456789abcd00,
+			wantBands:   8,
+			wantDiffers: 1,
+		},
+		{
+			name:        "top band differs",
+			a:           0x0123456789abcdef,
+			b:           -0x7edcba9876543211,
+			wantBands:   8,
+			wantDiffers: 1,
+		},
+		{
+			name:        "every band differs",
+			a:           0,
+			b:           0x0101010101010101,
+			wantBands:   8,
+			wantDiffers: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := fingerprintBands(tt.a)
+			b := fingerprintBands(tt.b)
+			if len(a) != tt.wantBands || len(b) != tt.wantBands {
+				t.Fatalf("fingerprintBands() returned %d and %d bands, want %d", len(a), len(b), tt.wantBands)
+			}
+
+			differs := 0
+			for i := range a {
+				if a[i] != b[i] {
+					differs += 1
+				}
+			}
+			if differs != tt.wantDiffers {
+				t.Errorf("%d bands differ, want %d", differs, tt.wantDiffers)
+			}
+		})
+	}
+}
+
+func TestNearDuplicates(t *testing.T) {
+	db := newTestDB(t)
+	config := &Config{
+		DuplicateThreshold: 0.8,
+		DuplicateWindow:    72 * time.Hour,
+	}
+
+	record := func(text string, createdAt time.Time) Mention {
+		mention := newMention(FilterItem{Source: "test", Text: text})
+		mention.CreatedAt = createdAt
+		if dbResult := db.Create(&mention); dbResult.Error != nil {
+			t.Fatalf("error creating mention: %v", dbResult.Error)
+		}
+		if err := recordFingerprintBands(mention, db); err != nil {
+			t.Fatalf("error recording bands: %v", err)
+		}
+		return mention
+	}
+
+	original := record(duplicateTestPost, time.Now().Add(-time.Hour))
+	record("Dokku 0.34 was released today with support for multiple builders and a new scheduler for running apps on k3s", time.Now().Add(-time.Hour))
+	record(duplicateTestPost, time.Now().Add(-100*time.Hour))
+
+	tests := []struct {
+		name    string
+		text    string
+		wantIDs []int32
+	}{
+		{
+			name:    "near-duplicate within the window",
+			text:    "Just moved all of my side projects from Heroku to a single Dokku server, deploys are as easy as a git push now",
+			wantIDs: []int32{original.ID},
+		},
+		{
+			name:    "unrelated",
+			text:    "Looking for a self-hosted alternative to Vercel that can run on a small VPS with a few docker containers",
+			wantIDs: nil,
+		},
+		{
+			name:    "too short to fingerprint",
+			text:    "dokku",
+			wantIDs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duplicates, err := nearDuplicates(newMention(FilterItem{Source: "test", Text: tt.text}), config, db)
+			if err != nil {
+				t.Fatalf("nearDuplicates() error = %v", err)
+			}
+
+			var ids []int32
+			for _, duplicate := range duplicates {
+				ids = append(ids, duplicate.ID)
+			}
+			if len(ids) != len(tt.wantIDs) || (len(ids) > 0 && ids[0] != tt.wantIDs[0]) {
+				t.Errorf("nearDuplicates() = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestMigrateDuplicates(t *testing.T) {
+	db := newTestDB(t)
+
+	// mentions recorded before bands were indexed
+	mention := newMention(FilterItem{Source: "test", Text: duplicateTestPost})
+	if dbResult := db.Create(&mention); dbResult.Error != nil {
+		t.Fatalf("error creating mention: %v", dbResult.Error)
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := migrateDuplicates(db); err != nil {
+			t.Fatalf("migrateDuplicates() error = %v", err)
+		}
+
+		var count int64
+		db.Model(&MentionBand{}).Where("mention_id = ?", mention.ID).Count(&count)
+		if count != int64(duplicateBands) {
+			t.Errorf("run %d: mention has %d bands, want %d", run, count, duplicateBands)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"golang.org/x/net/html/charset"
+	"gorm.io/gorm"
+)
+
+var feedIconURL = "https://upload.wikimedia.org/wikipedia/en/thumb/4/43/Feed-icon.svg/128px-Feed-icon.svg.png"
+
+type FeedEntry struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	FeedURL  string `gorm:"not null" form:"feed_url" json:"feed_url"`
+	EntryID  string `gorm:"not null" form:"entry_id" json:"entry_id"`
+	Title    string `gorm:"not null" form:"title" json:"title"`
+	Language string `form:"language" json:"language"`
+}
+
+type FeedState struct {
+	ID           int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	FeedURL      string `gorm:"not null" form:"feed_url" json:"feed_url"`
+	ETag         string `form:"etag" json:"etag"`
+	LastModified string `form:"last_modified" json:"last_modified"`
+}
+
+// FeedDocument decodes both rss 2.0 and atom documents. Only the fields for
+// the format being decoded will be populated.
+type FeedDocument struct {
+	XMLName xml.Name
+	Channel struct {
+		Title    string        `xml:"title"`
+		Link     string        `xml:"link"`
+		Language string        `xml:"language"`
+		Items    []FeedRSSItem `xml:"item"`
+	} `xml:"channel"`
+	Title   string          `xml:"title"`
+	Lang    string          `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Entries []FeedAtomEntry `xml:"entry"`
+}
+
+type FeedRSSItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string `xml:"category"`
+}
+
+type FeedAtomEntry struct {
+	ID        string `xml:"id"`
+	Title     string `xml:"title"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Author struct {
+		Name string `xml:"name"`
+		URI  string `xml:"uri"`
+	} `xml:"author"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+}
+
+// FeedItem is an entry from either an rss or atom feed
+type FeedItem struct {
+	FeedURL    string
+	FeedTitle  string
+	ID         string
+	Title      string
+	Link       string
+	Author     string
+	AuthorLink string
+	Summary    string
+	Content    string
+	Categories []string
+	Language   string
+	Published  time.Time
+}
+
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+}
+
+var feedTagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// FilterItem converts the entry for evaluation against the filter rules
+func (r FeedItem) FilterItem() FilterItem {
+	text := strings.Join([]string{r.Title, stripFeedHTML(r.Summary), stripFeedHTML(r.Content)}, " ")
+	return FilterItem{
+		Source:   "feed",
+		Text:     text,
+		Author:   r.Author,
+		Language: itemLanguage(r.Language, text),
+		URL:      r.Link,
+		Links:    []string{r.Link},
+	}
+}
+
+// parseFeedTime parses the various timestamp formats found in feeds
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// stripFeedHTML converts an html snippet into plain text
+func stripFeedHTML(value string) string {
+	return strings.TrimSpace(html.UnescapeString(feedTagRegexp.ReplaceAllString(value, "")))
+}
+
+// parseFeed decodes an rss or atom document into feed items
+func parseFeed(feedURL string, body []byte) ([]FeedItem, error) {
+	var items []FeedItem
+	var document FeedDocument
+	// not every feed is utf-8 encoded, so let the decoder convert from
+	// whatever charset the document declares
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(&document); err != nil {
+		return items, fmt.Errorf("error parsing feed %s: %w", feedURL, err)
+	}
+
+	for _, item := range document.Channel.Items {
+		id := item.GUID
+		if id == "" {
+			id = item.Link
+		}
+
+		author := item.Creator
+		if author == "" {
+			author = item.Author
+		}
+
+		items = append(items, FeedItem{
+			FeedURL:    feedURL,
+			FeedTitle:  document.Channel.Title,
+			ID:         id,
+			Title:      stripFeedHTML(item.Title),
+			Link:       item.Link,
+			Author:     author,
+			Summary:    stripFeedHTML(item.Description),
+			Content:    item.Content,
+			Categories: item.Categories,
+			Language:   document.Channel.Language,
+			Published:  parseFeedTime(item.PubDate),
+		})
+	}
+
+	for _, entry := range document.Entries {
+		link := ""
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+
+		// google alerts wraps the target in a redirect
+		if u, err := url.Parse(link); err == nil && u.Host == "www.google.com" && u.Path == "/url" && u.Query().Get("url") != "" {
+			link = u.Query().Get("url")
+		}
+
+		id := entry.ID
+		if id == "" {
+			id = link
+		}
+
+		published := parseFeedTime(entry.Published)
+		if published.IsZero() {
+			published = parseFeedTime(entry.Updated)
+		}
+
+		categories := []string{}
+		for _, category := range entry.Categories {
+			categories = append(categories, category.Term)
+		}
+
+		items = append(items, FeedItem{
+			FeedURL:    feedURL,
+			FeedTitle:  stripFeedHTML(document.Title),
+			ID:         id,
+			Title:      stripFeedHTML(entry.Title),
+			Link:       link,
+			Author:     entry.Author.Name,
+			AuthorLink: entry.Author.URI,
+			Summary:    stripFeedHTML(entry.Summary),
+			Content:    entry.Content,
+			Categories: categories,
+			Language:   document.Lang,
+			Published:  published,
+		})
+	}
+
+	return items, nil
+}
+
+// matchesFeedKeywords reports whether the item mentions any of the keywords.
+// Without keywords, the item is checked against the match expression instead.
+func matchesFeedKeywords(item FeedItem, config *Config) bool {
+	if len(config.FeedKeywords) == 0 {
+		values := append([]string{item.Title, stripFeedHTML(item.Summary), stripFeedHTML(item.Content)}, item.Categories...)
+		return config.Matcher.Verify("feed", item.ID, values...)
+	}
+
+	text := strings.ToLower(strings.Join([]string{item.Title, item.Summary, item.Content, item.Link}, " "))
+	for _, keyword := range config.FeedKeywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getFeedItems fetches a feed, returning no items if it has not changed
+// since the last fetch according to the stored etag or last-modified time.
+// The new etag and last-modified time are returned rather than saved, and
+// should only be saved with saveFeedState once the items have been
+// processed, as otherwise items that fail to be processed would never be
+// fetched again. The state is nil when the feed has not changed.
+func getFeedItems(feedURL string, db *gorm.DB) ([]FeedItem, *FeedState, error) {
+	var items []FeedItem
+	var state FeedState
+	if dbResult := db.First(&state, "feed_url = ?", feedURL); dbResult.Error != nil && !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+		return items, nil, fmt.Errorf("error fetching state for %s: %w", feedURL, dbResult.Error)
+	}
+
+	client := resty.New()
+	request := client.R()
+	if state.ETag != "" {
+		request.SetHeader("If-None-Match", state.ETag)
+	}
+	if state.LastModified != "" {
+		request.SetHeader("If-Modified-Since", state.LastModified)
+	}
+
+	resp, err := request.Get(feedURL)
+	if err != nil {
+		return items, nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotModified {
+		log.WithField("feed_url", feedURL).Info("Feed not modified")
+		return items, nil, nil
+	}
+
+	if resp.IsError() {
+		return items, nil, fmt.Errorf("error fetching feed %s: %s", feedURL, resp.Status())
+	}
+
+	items, err = parseFeed(feedURL, resp.Body())
+	if err != nil {
+		return items, nil, err
+	}
+
+	state.FeedURL = feedURL
+	state.ETag = resp.Header().Get("ETag")
+	state.LastModified = resp.Header().Get("Last-Modified")
+	return items, &state, nil
+}
+
+// saveFeedState stores the etag and last-modified time of a feed whose items
+// have all been processed
+func saveFeedState(state *FeedState, db *gorm.DB) error {
+	if state == nil {
+		return nil
+	}
+
+	if dbResult := db.Save(state); dbResult.Error != nil {
+		return fmt.Errorf("error saving state for %s: %w", state.FeedURL, dbResult.Error)
+	}
+
+	return nil
+}
+
+func sendSlackNotificationForFeedItem(result FeedItem, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"feed_url": result.FeedURL,
+		"entry_id": result.ID,
+		"title":    result.Title,
+	}
+
+	source := result.FeedTitle
+	if source == "" {
+		if u, err := url.Parse(result.FeedURL); err == nil {
+			source = u.Host
+		}
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   fmt.Sprintf("New article on %s!", source),
+		AuthorName: result.Author,
+		AuthorLink: result.AuthorLink,
+		Title:      result.Title,
+		TitleLink:  result.Link,
+		Footer:     "Feed Article Notification",
+		FooterIcon: feedIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.Published.Unix()), 10)),
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":rss:"),
+		slack.MsgOptionText(fmt.Sprintf("New article on <%s|%s>", result.Link, source), false),
+		slack.MsgOptionUsername("Feed Article Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processFeeds(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&FeedEntry{}, &FeedState{}); err != nil {
+		return fmt.Errorf("error migrating FeedEntry: %w", err)
+	}
+
+	if len(config.FeedUrls) == 0 {
+		log.Warn("No FEED_URLS specified, skipping feed")
+		return nil
+	}
+
+	inserted := 0
+	notified := 0
+	processed := 0
+	for _, feedURL := range config.FeedUrls {
+		log.WithField("feed_url", feedURL).Info("Fetching feed")
+		items, state, err := getFeedItems(feedURL, db)
+		if err != nil {
+			// a broken feed should not stop the others from being processed
+			log.WithError(err).WithField("feed_url", feedURL).Warn("error fetching feed, skipping feed")
+			continue
+		}
+
+		var results []FeedItem
+		for _, item := range items {
+			if !matchesFeedKeywords(item, config) {
+				continue
+			}
+
+			results = append(results, item)
+		}
+
+		processed += len(results)
+		log.WithFields(log.Fields{
+			"feed_url":    feedURL,
+			"entry_count": len(results),
+		}).Info("Processing entries")
+		for _, result := range results {
+			logFields := log.Fields{
+				"feed_url": result.FeedURL,
+				"entry_id": result.ID,
+				"title":    result.Title,
+			}
+
+			item := result.FilterItem()
+			if !config.Filters.Allow(item) {
+				continue
+			}
+
+			var entity FeedEntry
+			if dbResult := db.First(&entity, "feed_url = ? AND entry_id = ?", result.FeedURL, result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+				continue
+			}
+
+			log.WithFields(logFields).Info("Inserting new entry")
+			entity = FeedEntry{
+				FeedURL:  result.FeedURL,
+				EntryID:  result.ID,
+				Title:    result.Title,
+				Language: item.Language,
+			}
+
+			if dbResult := db.Create(&entity); dbResult.Error != nil {
+				log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting entry into database")
+				continue
+			}
+
+			inserted += 1
+			notifyConfig, err := prepareMention(item, config, db)
+			if err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error recording entry")
+				continue
+			}
+			if notifyConfig == nil {
+				continue
+			}
+
+			if err := sendSlackNotificationForFeedItem(result, notifyConfig); err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error posting entry to slack")
+				continue
+			}
+
+			if err := recordNotification(notifyConfig, db); err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error recording notification for entry")
+				continue
+			}
+
+			notified += 1
+		}
+
+		// only remember the feed as seen once its entries have been recorded
+		if err := saveFeedState(state, db); err != nil {
+			return err
+		}
+	}
+	log.WithFields(log.Fields{
+		"processed_entry_count": processed,
+		"inserted_entry_count":  inserted,
+		"notified_entry_count":  notified,
+	}).Info("Done with feed entries")
+
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultCompetitorWords are the names of competing projects, mentions of
+// which are always of interest
+var defaultCompetitorWords = []string{"caprover", "coolify", "heroku"}
+
+// defaultFilterRules are evaluated after any configured rules, and carry over
+// the noise filtering that twitter and bluesky have always had.
+//
+// Many posts in these languages refer to either:
+// - some pop artist's dog (kpop I think)
+// - count dooku (a mispelling from star wars)
+// - something crappy (telegu I believe)
+var defaultFilterRules = []FilterRule{
+	{
+		Action:   "allow",
+		Sources:  []string{"bluesky", "twitter"},
+		Field:    "text",
+		Contains: defaultCompetitorWords,
+	},
+	{
+		Action:  "deny",
+		Sources: []string{"bluesky", "twitter"},
+		Field:   "language",
+		Equals:  []string{"es", "et", "ja", "in", "it"},
+	},
+	{
+		Action:  "deny",
+		Sources: []string{"bluesky", "twitter"},
+		Field:   "text",
+		Contains: []string{
+			"caliphate",
+			"chennai",
+			"chatta",
+			"chettha",
+			"comte",
+			"conde",
+			"disney",
+			"dokkan",
+			"hera",
+			"imarat",
+			"isis",
+			"luke",
+			"kadyrov",
+			"movie",
+			"shiseru",
+			"sushi",
+			"tamil",
+			"theatre",
+			"theater",
+			"umarov",
+		},
+	},
+	{
+		Action:  "deny",
+		Sources: []string{"bluesky", "twitter"},
+		Field:   "author",
+		Equals:  []string{"dokku"},
+	},
+}
+
+// FilterRule allows or denies items where a single field matches. Text,
+// author, language and domain rules match when the field contains or
+// equals any of the given values, ignoring case. Metric rules match when the
+// named metric is within the inclusive min and max bounds.
+type FilterRule struct {
+	Action   string   `json:"action"`
+	Sources  []string `json:"sources,omitempty"`
+	Field    string   `json:"field"`
+	Metric   string   `json:"metric,omitempty"`
+	Contains []string `json:"contains,omitempty"`
+	Equals   []string `json:"equals,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+}
+
+// FilterItem is the shape every source converts its results to in order to
+// be filtered. Links are the urls the item shares, which are used to thread
+// notifications for items sharing the same url across sources.
+type FilterItem struct {
+	Source   string
+	Text     string
+	Author   string
+	Language string
+	URL      string
+	Links    []string
+	Metrics  map[string]float64
+}
+
+// FilterDecision is the outcome of evaluating an item, along with the rule
+// that decided it, which is nil when no rule matched
+type FilterDecision struct {
+	Allowed bool
+	Rule    *FilterRule
+}
+
+// FilterEngine evaluates items against an ordered list of rules
+type FilterEngine struct {
+	rules []FilterRule
+}
+
+// String describes the rule for logging purposes
+func (r FilterRule) String() string {
+	sources := "all sources"
+	if len(r.Sources) > 0 {
+		sources = strings.Join(r.Sources, ",")
+	}
+
+	field := r.Field
+	if r.Field == "metric" {
+		field = fmt.Sprintf("metric %s", r.Metric)
+	}
+
+	return fmt.Sprintf("%s %s on %s", r.Action, field, sources)
+}
+
+// validate reports whether the rule can be evaluated
+func (r FilterRule) validate() error {
+	if r.Action != "allow" && r.Action != "deny" {
+		return fmt.Errorf("invalid action %q, must be one of allow or deny", r.Action)
+	}
+
+	switch r.Field {
+	case "text", "author", "language", "domain":
+		if len(r.Contains) == 0 && len(r.Equals) == 0 {
+			return fmt.Errorf("%s rule must specify contains or equals", r.Field)
+		}
+	case "metric":
+		if r.Metric == "" {
+			return fmt.Errorf("metric rule must specify a metric")
+		}
+		if r.Min == nil && r.Max == nil {
+			return fmt.Errorf("metric rule must specify min or max")
+		}
+	default:
+		return fmt.Errorf("invalid field %q, must be one of text, author, language, domain or metric", r.Field)
+	}
+
+	return nil
+}
+
+// appliesTo reports whether the rule is global or applies to the source
+func (r FilterRule) appliesTo(source string) bool {
+	return len(r.Sources) == 0 || slices.Contains(r.Sources, source)
+}
+
+// matchesValue checks a field value against the contains and equals lists
+func (r FilterRule) matchesValue(value string) bool {
+	value = strings.ToLower(value)
+	if value == "" {
+		return false
+	}
+
+	for _, v := range r.Equals {
+		if value == strings.ToLower(v) {
+			return true
+		}
+	}
+
+	for _, v := range r.Contains {
+		if strings.Contains(value, strings.ToLower(v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesDomain checks a url against the rule, where equals also matches
+// subdomains of the listed domains
+func (r FilterRule) matchesDomain(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for _, domain := range r.Equals {
+		domain = strings.TrimPrefix(strings.ToLower(domain), "www.")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	for _, v := range r.Contains {
+		if strings.Contains(host, strings.ToLower(v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches reports whether the rule applies to the item
+func (r FilterRule) Matches(item FilterItem) bool {
+	if !r.appliesTo(item.Source) {
+		return false
+	}
+
+	switch r.Field {
+	case "text":
+		return r.matchesValue(item.Text)
+	case "author":
+		return r.matchesValue(item.Author)
+	case "language":
+		return r.matchesValue(item.Language)
+	case "domain":
+		return r.matchesDomain(item.URL)
+	case "metric":
+		value, ok := item.Metrics[r.Metric]
+		if !ok {
+			return false
+		}
+		if r.Min != nil && value < *r.Min {
+			return false
+		}
+		if r.Max != nil && value > *r.Max {
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// newFilterEngine orders the rules so that rules for specific sources take
+// precedence over global rules, with the defaults evaluated last. Within
+// each group, rules keep the order they were configured in.
+func newFilterEngine(rules []FilterRule) (*FilterEngine, error) {
+	var sourceRules []FilterRule
+	var globalRules []FilterRule
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid filter rule %d: %w", i+1, err)
+		}
+
+		if len(rule.Sources) > 0 {
+			sourceRules = append(sourceRules, rule)
+		} else {
+			globalRules = append(globalRules, rule)
+		}
+	}
+
+	ordered := append(sourceRules, globalRules...)
+	ordered = append(ordered, defaultFilterRules...)
+	return &FilterEngine{rules: ordered}, nil
+}
+
+// loadFilterRules reads rules from the FILTER_RULES_FILE json file, followed
+// by any in the FILTER_RULES json array
+func loadFilterRules(config *Config) ([]FilterRule, error) {
+	var rules []FilterRule
+	if config.FilterRulesFile != "" {
+		data, err := os.ReadFile(config.FilterRulesFile)
+		if err != nil {
+			return rules, fmt.Errorf("error reading filter rules file: %w", err)
+		}
+
+		var fileRules []FilterRule
+		if err := json.Unmarshal(data, &fileRules); err != nil {
+			return rules, fmt.Errorf("error parsing filter rules file: %w", err)
+		}
+
+		rules = append(rules, fileRules...)
+	}
+
+	if config.FilterRules != "" {
+		var envRules []FilterRule
+		if err := json.Unmarshal([]byte(config.FilterRules), &envRules); err != nil {
+			return rules, fmt.Errorf("error parsing FILTER_RULES: %w", err)
+		}
+
+		rules = append(rules, envRules...)
+	}
+
+	return rules, nil
+}
+
+// Evaluate returns the decision of the first rule matching the item,
+// allowing items that no rule matches
+func (e *FilterEngine) Evaluate(item FilterItem) FilterDecision {
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.Matches(item) {
+			continue
+		}
+
+		return FilterDecision{
+			Allowed: rule.Action == "allow",
+			Rule:    rule,
+		}
+	}
+
+	return FilterDecision{Allowed: true}
+}
+
+// Allow reports whether the item passes the filter rules, logging the rule
+// responsible when it does not
+func (e *FilterEngine) Allow(item FilterItem) bool {
+	decision := e.Evaluate(item)
+	if !decision.Allowed {
+		log.WithFields(log.Fields{
+			"source": item.Source,
+			"url":    item.URL,
+			"rule":   decision.Rule.String(),
+		}).Info("Filtered out")
+	}
+
+	return decision.Allowed
+}
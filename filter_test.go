@@ -0,0 +1,249 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func float64Pointer(v float64) *float64 {
+	return &v
+}
+
+func TestFilterRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    FilterRule
+		wantErr bool
+	}{
+		{
+			name: "text rule",
+			rule: FilterRule{Action: "deny", Field: "text", Contains: []string{"dooku"}},
+		},
+		{
+			name: "metric rule",
+			rule: FilterRule{Action: "allow", Field: "metric", Metric: "score", Min: float64Pointer(10)},
+		},
+		{
+			name:    "invalid action",
+			rule:    FilterRule{Action: "block", Field: "text", Contains: []string{"dooku"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid field",
+			rule:    FilterRule{Action: "deny", Field: "title", Contains: []string{"dooku"}},
+			wantErr: true,
+		},
+		{
+			name:    "text rule without values",
+			rule:    FilterRule{Action: "deny", Field: "text"},
+			wantErr: true,
+		},
+		{
+			name:    "metric rule without a metric",
+			rule:    FilterRule{Action: "deny", Field: "metric", Max: float64Pointer(1)},
+			wantErr: true,
+		},
+		{
+			name:    "metric rule without bounds",
+			rule:    FilterRule{Action: "deny", Field: "metric", Metric: "score"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterRuleMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		rule FilterRule
+		item FilterItem
+		want bool
+	}{
+		{
+			name: "text contains ignores case",
+			rule: FilterRule{Action: "deny", Field: "text", Contains: []string{"Dooku"}},
+			item: FilterItem{Source: "reddit", Text: "count DOOKU was right"},
+			want: true,
+		},
+		{
+			name: "text equals must match the whole value",
+			rule: FilterRule{Action: "deny", Field: "text", Equals: []string{"dokku"}},
+			item: FilterItem{Source: "reddit", Text: "trying out dokku"},
+			want: false,
+		},
+		{
+			name: "author equals",
+			rule: FilterRule{Action: "deny", Field: "author", Equals: []string{"dokku"}},
+			item: FilterItem{Source: "twitter", Author: "Dokku"},
+			want: true,
+		},
+		{
+			name: "empty language never matches",
+			rule: FilterRule{Action: "deny", Field: "language", Contains: []string{""}},
+			item: FilterItem{Source: "twitter"},
+			want: false,
+		},
+		{
+			name: "other sources are ignored",
+			rule: FilterRule{Action: "deny", Sources: []string{"twitter"}, Field: "text", Contains: []string{"dokku"}},
+			item: FilterItem{Source: "reddit", Text: "dokku"},
+			want: false,
+		},
+		{
+			name: "domain equals matches subdomains",
+			rule: FilterRule{Action: "deny", Field: "domain", Equals: []string{"www.example.com"}},
+			item: FilterItem{Source: "feed", URL: "https://blog.example.com/post"},
+			want: true,
+		},
+		{
+			name: "domain equals does not match suffixes",
+			rule: FilterRule{Action: "deny", Field: "domain", Equals: []string{"example.com"}},
+			item: FilterItem{Source: "feed", URL: "https://notexample.com/post"},
+			want: false,
+		},
+		{
+			name: "domain contains",
+			rule: FilterRule{Action: "deny", Field: "domain", Contains: []string{"spam"}},
+			item: FilterItem{Source: "feed", URL: "https://spamblog.net/post"},
+			want: true,
+		},
+		{
+			name: "domain without a url",
+			rule: FilterRule{Action: "deny", Field: "domain", Contains: []string{"example"}},
+			item: FilterItem{Source: "feed", URL: "example"},
+			want: false,
+		},
+		{
+			name: "metric within bounds",
+			rule: FilterRule{Action: "deny", Field: "metric", Metric: "points", Min: float64Pointer(1), Max: float64Pointer(5)},
+			item: FilterItem{Source: "hackernews_story", Metrics: map[string]float64{"points": 5}},
+			want: true,
+		},
+		{
+			name: "metric below min",
+			rule: FilterRule{Action: "deny", Field: "metric", Metric: "points", Min: float64Pointer(1)},
+			item: FilterItem{Source: "hackernews_story", Metrics: map[string]float64{"points": 0}},
+			want: false,
+		},
+		{
+			name: "metric above max",
+			rule: FilterRule{Action: "deny", Field: "metric", Metric: "points", Max: float64Pointer(5)},
+			item: FilterItem{Source: "hackernews_story", Metrics: map[string]float64{"points": 6}},
+			want: false,
+		},
+		{
+			name: "missing metric",
+			rule: FilterRule{Action: "deny", Field: "metric", Metric: "points", Max: float64Pointer(5)},
+			item: FilterItem{Source: "reddit"},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(tt.item); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterEngineEvaluate(t *testing.T) {
+	rules := []FilterRule{
+		{Action: "deny", Field: "text", Contains: []string{"dokku"}},
+		{Action: "allow", Sources: []string{"reddit"}, Field: "text", Contains: []string{"dokku"}},
+		{Action: "allow", Field: "author", Equals: []string{"josegonzalez"}},
+		{Action: "deny", Field: "author", Equals: []string{"josegonzalez"}},
+	}
+
+	engine, err := newFilterEngine(rules)
+	if err != nil {
+		t.Fatalf("newFilterEngine() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		item        FilterItem
+		wantAllowed bool
+		wantRule    *FilterRule
+	}{
+		{
+			name:        "source rules take precedence over global rules",
+			item:        FilterItem{Source: "reddit", Text: "deploying with dokku"},
+			wantAllowed: true,
+			wantRule:    &rules[1],
+		},
+		{
+			name:        "global rules apply to other sources",
+			item:        FilterItem{Source: "lemmy", Text: "deploying with dokku"},
+			wantAllowed: false,
+			wantRule:    &rules[0],
+		},
+		{
+			name:        "global rules keep their configured order",
+			item:        FilterItem{Source: "lemmy", Author: "josegonzalez"},
+			wantAllowed: true,
+			wantRule:    &rules[2],
+		},
+		{
+			name:        "configured rules take precedence over defaults",
+			item:        FilterItem{Source: "twitter", Text: "dokku on a vps", Author: "dokku"},
+			wantAllowed: false,
+			wantRule:    &rules[0],
+		},
+		{
+			name:        "default rules are evaluated last",
+			item:        FilterItem{Source: "twitter", Text: "count dooku", Author: "dokku"},
+			wantAllowed: false,
+			wantRule:    &defaultFilterRules[3],
+		},
+		{
+			name:        "default competitor rule allows denied languages",
+			item:        FilterItem{Source: "bluesky", Text: "adiós heroku", Language: "es"},
+			wantAllowed: true,
+			wantRule:    &defaultFilterRules[0],
+		},
+		{
+			name:        "unmatched items are allowed",
+			item:        FilterItem{Source: "lemmy", Text: "self-hosting a paas"},
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(tt.item)
+			if decision.Allowed != tt.wantAllowed {
+				t.Errorf("Evaluate() allowed = %v, want %v", decision.Allowed, tt.wantAllowed)
+			}
+
+			if tt.wantRule == nil {
+				if decision.Rule != nil {
+					t.Errorf("Evaluate() rule = %s, want none", decision.Rule)
+				}
+				return
+			}
+
+			if decision.Rule == nil || !reflect.DeepEqual(*decision.Rule, *tt.wantRule) {
+				t.Errorf("Evaluate() rule = %v, want %s", decision.Rule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestNewFilterEngineInvalidRule(t *testing.T) {
+	_, err := newFilterEngine([]FilterRule{
+		{Action: "deny", Field: "text", Contains: []string{"dooku"}},
+		{Action: "deny", Field: "text"},
+	})
+	if err == nil {
+		t.Fatal("newFilterEngine() error = nil, want error")
+	}
+}
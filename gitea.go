@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var codebergIconURL = "https://codeberg.org/assets/img/favicon.png"
+
+var giteaIconURL = "https://gitea.com/assets/img/favicon.png"
+
+var codebergForge = Forge{
+	Service:   "codeberg",
+	Name:      "Codeberg",
+	IconURL:   codebergIconURL,
+	IconEmoji: ":codeberg:",
+}
+
+var giteaForge = Forge{
+	Service:   "gitea",
+	Name:      "Gitea",
+	IconURL:   giteaIconURL,
+	IconEmoji: ":gitea:",
+}
+
+// giteaMaxPages caps how many pages are fetched per instance on a single run
+var giteaMaxPages = 5
+
+type GiteaSearchResponse struct {
+	OK   bool                  `json:"ok"`
+	Data []GiteaRepositoryItem `json:"data"`
+}
+
+type GiteaRepositoryItem struct {
+	ID    int `json:"id"`
+	Owner struct {
+		ID        int    `json:"id"`
+		Login     string `json:"login"`
+		FullName  string `json:"full_name"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"owner"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	Description   string    `json:"description"`
+	Fork          bool      `json:"fork"`
+	Mirror        bool      `json:"mirror"`
+	HTMLURL       string    `json:"html_url"`
+	Language      string    `json:"language"`
+	StarsCount    int       `json:"stars_count"`
+	ForksCount    int       `json:"forks_count"`
+	DefaultBranch string    `json:"default_branch"`
+	Archived      bool      `json:"archived"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// getGiteaRepositories searches a gitea or forgejo instance for repositories
+// matching the tag
+func getGiteaRepositories(instance string, forge Forge, config *Config) ([]RepositoryResult, error) {
+	var results []RepositoryResult
+	host := instanceHost(instance)
+	for page := 1; page <= giteaMaxPages; page++ {
+		log.WithFields(log.Fields{
+			"instance": host,
+			"page":     page,
+		}).Info("Fetching page")
+
+		var response GiteaSearchResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"q":     config.Tag,
+				"limit": "50",
+				"page":  strconv.FormatInt(int64(page), 10),
+				"sort":  "created",
+				"order": "desc",
+			}).
+			SetResult(&response).
+			Get(fmt.Sprintf("%s/api/v1/repos/search", instanceURL(instance)))
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching repositories on %s: %s", host, resp.Status())
+		}
+
+		if len(response.Data) == 0 {
+			break
+		}
+
+		for _, repository := range response.Data {
+			if !config.Matcher.Verify(forge.Service, repository.HTMLURL, repository.FullName, repository.Description) {
+				continue
+			}
+
+			results = append(results, RepositoryResult{
+				Forge:          forge,
+				Host:           host,
+				RepositoryID:   int64(repository.ID),
+				FullName:       repository.FullName,
+				Description:    repository.Description,
+				URL:            repository.HTMLURL,
+				OwnerLogin:     repository.Owner.Login,
+				OwnerAvatarURL: repository.Owner.AvatarURL,
+				OwnerURL:       fmt.Sprintf("%s/%s", instanceURL(instance), repository.Owner.Login),
+				Language:       repository.Language,
+				Stars:          repository.StarsCount,
+				CreatedAt:      repository.CreatedAt,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func processCodebergRepositories(config *Config, db *gorm.DB) error {
+	log.Info("Fetching repositories")
+	results, err := getGiteaRepositories("codeberg.org", codebergForge, config)
+	if err != nil {
+		return err
+	}
+
+	return processForgeRepositories(results, nil, config, db)
+}
+
+func processGiteaRepositories(config *Config, db *gorm.DB) error {
+	if len(config.GiteaInstances) == 0 {
+		log.Warn("No GITEA_INSTANCES specified, skipping gitea")
+		return nil
+	}
+
+	var results []RepositoryResult
+	for _, instance := range config.GiteaInstances {
+		log.WithField("instance", instanceHost(instance)).Info("Fetching repositories")
+		instanceResults, err := getGiteaRepositories(instance, giteaForge, config)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, instanceResults...)
+	}
+
+	return processForgeRepositories(results, nil, config, db)
+}
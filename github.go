@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -9,7 +8,6 @@ import (
 
 	"github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
-	"github.com/slack-go/slack"
 	"gorm.io/gorm"
 )
 
@@ -19,6 +17,7 @@ type GithubRepository struct {
 	ID           int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
 	RepositoryID int64  `gorm:"not null" form:"repository_id" json:"repository_id"`
 	Title        string `gorm:"not null" form:"title" json:"title"`
+	Language     string `form:"language" json:"language"`
 }
 
 type GithubResponse struct {
@@ -35,7 +34,7 @@ type GithubRepositoryItem struct {
 	Private                  bool           `json:"private"`
 	Owner                    GithubUserItem `json:"owner"`
 	HTMLURL                  string         `json:"html_url"`
-	Description              interface{}    `json:"description"`
+	Description              string         `json:"description"`
 	Fork                     bool           `json:"fork"`
 	URL                      string         `json:"url"`
 	ForksURL                 string         `json:"forks_url"`
@@ -156,59 +155,39 @@ func getGithubRepositories(config *Config) ([]GithubRepositoryItem, error) {
 			break
 		}
 
-		results = append(results, response.Items...)
+		for _, item := range response.Items {
+			if !config.Matcher.Verify("github", strconv.FormatInt(int64(item.ID), 10), item.FullName, item.Description) {
+				continue
+			}
+
+			results = append(results, item)
+		}
 	}
 
 	return results, nil
 }
 
-func sendSlackNotificationForGithubRepository(result GithubRepositoryItem, config *Config) error {
-	if !config.NotifySlack {
-		return nil
-	}
-
-	logFields := log.Fields{
-		"repository_id": result.ID,
-	}
-
-	fields := []slack.AttachmentField{
-		{
-			Title: "Language",
-			Value: result.Language,
-			Short: true,
-		},
-	}
-
-	attachment := slack.Attachment{
-		Color:      "#36a64f",
-		Fallback:   "New repository on Github!",
-		AuthorName: result.Owner.Login,
-		AuthorIcon: result.Owner.AvatarURL,
-		AuthorLink: result.Owner.HTMLURL,
-		Title:      result.FullName,
-		TitleLink:  result.HTMLURL,
-		Footer:     "Github Repository Notification",
-		FooterIcon: githubIconURL,
-		Ts:         json.Number(strconv.FormatInt(int64(result.CreatedAt.Unix()), 10)),
-		Fields:     fields,
-	}
-
-	log.WithFields(logFields).Info("Notifying slack")
-	messageOpts := []slack.MsgOption{
-		slack.MsgOptionAsUser(false),
-		slack.MsgOptionAttachments(attachment),
-		slack.MsgOptionIconEmoji(":github:"),
-		slack.MsgOptionText("New repository on <"+result.HTMLURL+"|Github>", false),
-		slack.MsgOptionUsername("Github Repository Notifications"),
-		slack.MsgOptionDisableLinkUnfurl(),
-	}
-
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
+// RepositoryResult normalizes the repository into the shape shared with
+// other forges
+func (r GithubRepositoryItem) RepositoryResult() RepositoryResult {
+	return RepositoryResult{
+		Forge:          githubForge,
+		Host:           "github.com",
+		RepositoryID:   int64(r.ID),
+		FullName:       r.FullName,
+		Description:    r.Description,
+		URL:            r.HTMLURL,
+		OwnerLogin:     r.Owner.Login,
+		OwnerAvatarURL: r.Owner.AvatarURL,
+		OwnerURL:       r.Owner.HTMLURL,
+		Language:       r.Language,
+		Stars:          r.StargazersCount,
+		CreatedAt:      r.CreatedAt,
 	}
+}
 
-	return nil
+func sendSlackNotificationForGithubRepository(result GithubRepositoryItem, config *Config) error {
+	return sendSlackNotificationForRepository(result.RepositoryResult(), config)
 }
 
 func processGithubRepositories(config *Config, db *gorm.DB) error {
@@ -231,6 +210,11 @@ func processGithubRepositories(config *Config, db *gorm.DB) error {
 			"title":         result.FullName,
 		}
 
+		item := result.RepositoryResult().FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
 		var entity GithubRepository
 		if dbResult := db.First(&entity, "repository_id = ?", result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
 			continue
@@ -240,6 +224,7 @@ func processGithubRepositories(config *Config, db *gorm.DB) error {
 		entity = GithubRepository{
 			RepositoryID: int64(result.ID),
 			Title:        result.FullName,
+			Language:     item.Language,
 		}
 
 		if dbResult := db.Create(&entity); dbResult.Error != nil {
@@ -248,11 +233,25 @@ func processGithubRepositories(config *Config, db *gorm.DB) error {
 		}
 
 		inserted += 1
-		if err := sendSlackNotificationForGithubRepository(result, config); err != nil {
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording repository")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForGithubRepository(result, notifyConfig); err != nil {
 			log.WithError(err).WithFields(logFields).Fatal("error posting repository to slack")
 			continue
 		}
 
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for repository")
+			continue
+		}
+
 		notified += 1
 	}
 	log.WithFields(log.Fields{
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+// githubCodeMaxPages caps how many search pages are fetched per query on a
+// single run
+var githubCodeMaxPages = 5
+
+// githubCodeSearchInterval spaces out requests, as code search is limited to
+// 10 requests per minute
+var githubCodeSearchInterval = 6 * time.Second
+
+type GithubCode struct {
+	ID           int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	RepositoryID int64  `gorm:"not null" form:"repository_id" json:"repository_id"`
+	FullName     string `gorm:"not null" form:"full_name" json:"full_name"`
+	Path         string `gorm:"not null" form:"path" json:"path"`
+	Language     string `form:"language" json:"language"`
+}
+
+type GithubCodeResponse struct {
+	TotalCount        int              `json:"total_count"`
+	IncompleteResults bool             `json:"incomplete_results"`
+	Items             []GithubCodeItem `json:"items"`
+}
+
+type GithubCodeItem struct {
+	Name       string               `json:"name"`
+	Path       string               `json:"path"`
+	Sha        string               `json:"sha"`
+	URL        string               `json:"url"`
+	GitURL     string               `json:"git_url"`
+	HTMLURL    string               `json:"html_url"`
+	Repository GithubRepositoryItem `json:"repository"`
+	Score      float64              `json:"score"`
+	Query      string               `json:"-"`
+}
+
+// FilterItem converts the code match for evaluation against the filter rules
+func (r GithubCodeItem) FilterItem() FilterItem {
+	return FilterItem{
+		Source:   "github_code",
+		Text:     strings.Join([]string{r.Repository.FullName, r.Path}, " "),
+		Author:   r.Repository.Owner.Login,
+		Language: detectLanguage(r.Repository.Description),
+		URL:      r.HTMLURL,
+		Links:    []string{r.Repository.HTMLURL},
+		Metrics: map[string]float64{
+			"stars": float64(r.Repository.StargazersCount),
+		},
+	}
+}
+
+func getGithubCode(query string, config *Config) ([]GithubCodeItem, error) {
+	var results []GithubCodeItem
+	for page := 1; page <= githubCodeMaxPages; page++ {
+		log.WithFields(log.Fields{
+			"query": query,
+			"page":  page,
+		}).Info("Fetching page")
+
+		var response GithubCodeResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetAuthToken(config.GithubToken).
+			SetQueryParams(map[string]string{
+				"q":        query,
+				"per_page": "100",
+				"page":     strconv.FormatInt(int64(page), 10),
+			}).
+			SetResult(&response).
+			Get("https://api.github.com/search/code")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching github code: %s", resp.Status())
+		}
+
+		for _, item := range response.Items {
+			if isIgnoredGithubOwner(item.Repository.FullName, config) {
+				continue
+			}
+
+			item.Query = query
+			results = append(results, item)
+		}
+
+		if len(response.Items) < 100 {
+			break
+		}
+
+		time.Sleep(githubCodeSearchInterval)
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForGithubCode(result GithubCodeItem, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"repository_id": result.Repository.ID,
+		"path":          result.Path,
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "File",
+			Value: fmt.Sprintf("<%s|%s>", result.HTMLURL, result.Path),
+			Short: true,
+		},
+		{
+			Title: "Query",
+			Value: result.Query,
+			Short: true,
+		},
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New adopter on Github!",
+		AuthorName: result.Repository.Owner.Login,
+		AuthorIcon: result.Repository.Owner.AvatarURL,
+		AuthorLink: result.Repository.Owner.HTMLURL,
+		Title:      result.Repository.FullName,
+		TitleLink:  result.Repository.HTMLURL,
+		Footer:     "Github Code Notification",
+		FooterIcon: githubIconURL,
+		Ts:         json.Number(strconv.FormatInt(time.Now().Unix(), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":github:"),
+		slack.MsgOptionText("New adopter on <"+result.Repository.HTMLURL+"|Github>", false),
+		slack.MsgOptionUsername("Github Code Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processGithubCode(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&GithubCode{}); err != nil {
+		return fmt.Errorf("error migrating GithubCode: %w", err)
+	}
+
+	if config.GithubToken == "" {
+		log.Warn("No GITHUB_TOKEN specified, skipping github code")
+		return nil
+	}
+
+	if len(config.GithubCodeQueries) == 0 {
+		log.Warn("No GITHUB_CODE_QUERIES specified, skipping github code")
+		return nil
+	}
+
+	var results []GithubCodeItem
+	for i, query := range config.GithubCodeQueries {
+		if i > 0 {
+			time.Sleep(githubCodeSearchInterval)
+		}
+
+		log.WithField("query", query).Info("Fetching code")
+		queryResults, err := getGithubCode(query, config)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, queryResults...)
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("code_count", len(results)).Info("Processing code")
+	for _, result := range results {
+		logFields := log.Fields{
+			"repository_id": result.Repository.ID,
+			"title":         result.Repository.FullName,
+			"path":          result.Path,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity GithubCode
+		if dbResult := db.First(&entity, "repository_id = ? AND path = ?", result.Repository.ID, result.Path); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		// only the first matching file in a repository marks a new adopter,
+		// further matches are recorded without notifying
+		var existing GithubCode
+		isNewAdopter := errors.Is(db.First(&existing, "repository_id = ?", result.Repository.ID).Error, gorm.ErrRecordNotFound)
+
+		log.WithFields(logFields).Info("Inserting new code")
+		entity = GithubCode{
+			RepositoryID: int64(result.Repository.ID),
+			FullName:     result.Repository.FullName,
+			Path:         result.Path,
+			Language:     item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting code into database")
+			continue
+		}
+
+		inserted += 1
+		if !isNewAdopter {
+			continue
+		}
+
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording code")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForGithubCode(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting code to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for code")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_code_count": len(results),
+		"inserted_code_count":  inserted,
+		"notified_code_count":  notified,
+	}).Info("Done with github code")
+
+	return nil
+}
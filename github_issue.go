@@ -0,0 +1,556 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+// githubIssueMaxPages caps how many search pages are fetched on a single run,
+// as the search api will not return more than 1000 results regardless
+var githubIssueMaxPages = 10
+
+// githubIssueLookback is how far back the first search looks, so that the
+// first run does not announce every issue that has ever mentioned the tag
+var githubIssueLookback = 7 * 24 * time.Hour
+
+// githubIssueOverlap is how far before the newest recorded item later
+// searches start, as the search index can lag behind new items
+var githubIssueOverlap = 24 * time.Hour
+
+type GithubIssue struct {
+	ID       int32      `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	NodeID   string     `gorm:"not null" form:"node_id" json:"node_id"`
+	Kind     string     `gorm:"not null" form:"kind" json:"kind"`
+	Title    string     `gorm:"not null" form:"title" json:"title"`
+	Language string     `form:"language" json:"language"`
+	OpenedAt *time.Time `form:"opened_at" json:"opened_at"`
+}
+
+type GithubIssueSearchResponse struct {
+	TotalCount        int               `json:"total_count"`
+	IncompleteResults bool              `json:"incomplete_results"`
+	Items             []GithubIssueItem `json:"items"`
+}
+
+type GithubIssueItem struct {
+	ID     int            `json:"id"`
+	NodeID string         `json:"node_id"`
+	Number int            `json:"number"`
+	Title  string         `json:"title"`
+	User   GithubUserItem `json:"user"`
+	Labels []struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	State         string     `json:"state"`
+	Locked        bool       `json:"locked"`
+	Comments      int        `json:"comments"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ClosedAt      *time.Time `json:"closed_at"`
+	Body          string     `json:"body"`
+	HTMLURL       string     `json:"html_url"`
+	RepositoryURL string     `json:"repository_url"`
+	PullRequest   *struct {
+		URL      string     `json:"url"`
+		HTMLURL  string     `json:"html_url"`
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+type GithubDiscussionSearchResponse struct {
+	Data struct {
+		Search struct {
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+			Nodes []GithubDiscussionItem `json:"nodes"`
+		} `json:"search"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type GithubDiscussionItem struct {
+	ID         string    `json:"id"`
+	Number     int       `json:"number"`
+	Title      string    `json:"title"`
+	BodyText   string    `json:"bodyText"`
+	URL        string    `json:"url"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Closed     bool      `json:"closed"`
+	IsAnswered bool      `json:"isAnswered"`
+	Author     struct {
+		Login     string `json:"login"`
+		AvatarURL string `json:"avatarUrl"`
+		URL       string `json:"url"`
+	} `json:"author"`
+	Repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+		Owner         struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+	Category struct {
+		Name string `json:"name"`
+	} `json:"category"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Comments struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"comments"`
+}
+
+// GithubIssueResult normalizes issues, pull requests and discussions into a
+// single shape for notifying
+type GithubIssueResult struct {
+	NodeID          string
+	Kind            string
+	Repository      string
+	Number          int
+	Title           string
+	URL             string
+	State           string
+	Labels          []string
+	Comments        int
+	AuthorLogin     string
+	AuthorAvatarURL string
+	AuthorURL       string
+	CreatedAt       time.Time
+}
+
+// FilterItem converts the issue for evaluation against the filter rules
+func (r GithubIssueResult) FilterItem() FilterItem {
+	text := strings.Join([]string{r.Repository, r.Title}, " ")
+	return FilterItem{
+		Source:   "github_issue",
+		Text:     text,
+		Author:   r.AuthorLogin,
+		Language: detectLanguage(text),
+		URL:      r.URL,
+		Links:    []string{r.URL},
+		Metrics: map[string]float64{
+			"comments": float64(r.Comments),
+		},
+	}
+}
+
+var githubDiscussionSearchQuery = `query($query: String!, $cursor: String) {
+  search(query: $query, type: DISCUSSION, first: 50, after: $cursor) {
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+    nodes {
+      ... on Discussion {
+        id
+        number
+        title
+        bodyText
+        url
+        createdAt
+        closed
+        isAnswered
+        author {
+          login
+          avatarUrl
+          url
+        }
+        repository {
+          nameWithOwner
+          owner {
+            login
+          }
+        }
+        category {
+          name
+        }
+        labels(first: 10) {
+          nodes {
+            name
+          }
+        }
+        comments {
+          totalCount
+        }
+      }
+    }
+  }
+}`
+
+// githubIssueSince returns the time to search for items created after,
+// which is shortly before the newest recorded item
+func githubIssueSince(db *gorm.DB) (time.Time, error) {
+	var latest GithubIssue
+	dbResult := db.Where("opened_at IS NOT NULL").Order("opened_at desc").First(&latest)
+	if errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+		return time.Now().Add(-githubIssueLookback), nil
+	}
+	if dbResult.Error != nil {
+		return time.Time{}, fmt.Errorf("error fetching latest issue: %w", dbResult.Error)
+	}
+
+	return latest.OpenedAt.Add(-githubIssueOverlap), nil
+}
+
+// githubSearchQuery builds a search query for items mentioning the tag that
+// were created after since, excluding any ignored organizations
+func githubSearchQuery(config *Config, since time.Time) string {
+	query := []string{config.Tag, fmt.Sprintf("created:>%s", since.UTC().Format(time.RFC3339))}
+	for _, org := range config.GithubIgnoreOrgs {
+		query = append(query, fmt.Sprintf("-org:%s", org))
+	}
+
+	return strings.Join(query, " ")
+}
+
+// isIgnoredGithubOwner reports whether the repository belongs to an ignored
+// organization, as the search qualifiers are not always honored
+func isIgnoredGithubOwner(repository string, config *Config) bool {
+	owner := strings.ToLower(strings.SplitN(repository, "/", 2)[0])
+	for _, org := range config.GithubIgnoreOrgs {
+		if owner == strings.ToLower(org) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getGithubIssues(since time.Time, config *Config) ([]GithubIssueResult, error) {
+	var results []GithubIssueResult
+	for page := 1; page <= githubIssueMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		var response GithubIssueSearchResponse
+		client := resty.New()
+		request := client.R().
+			SetQueryParams(map[string]string{
+				"q":        githubSearchQuery(config, since),
+				"per_page": "100",
+				"page":     strconv.FormatInt(int64(page), 10),
+				"sort":     "created",
+				"order":    "desc",
+			}).
+			SetResult(&response)
+		if config.GithubToken != "" {
+			request.SetAuthToken(config.GithubToken)
+		}
+
+		resp, err := request.Get("https://api.github.com/search/issues")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching github issues: %s", resp.Status())
+		}
+
+		if len(response.Items) == 0 {
+			break
+		}
+
+		for _, item := range response.Items {
+			repository := strings.TrimPrefix(item.RepositoryURL, "https://api.github.com/repos/")
+			if isIgnoredGithubOwner(repository, config) {
+				continue
+			}
+
+			if !config.Matcher.Verify("github_issue", item.NodeID, item.Title, item.Body) {
+				continue
+			}
+
+			kind := "issue"
+			state := item.State
+			if item.PullRequest != nil {
+				kind = "pull request"
+				if item.PullRequest.MergedAt != nil {
+					state = "merged"
+				}
+			}
+
+			labels := []string{}
+			for _, label := range item.Labels {
+				labels = append(labels, label.Name)
+			}
+
+			results = append(results, GithubIssueResult{
+				NodeID:          item.NodeID,
+				Kind:            kind,
+				Repository:      repository,
+				Number:          item.Number,
+				Title:           item.Title,
+				URL:             item.HTMLURL,
+				State:           state,
+				Labels:          labels,
+				Comments:        item.Comments,
+				AuthorLogin:     item.User.Login,
+				AuthorAvatarURL: item.User.AvatarURL,
+				AuthorURL:       item.User.HTMLURL,
+				CreatedAt:       item.CreatedAt,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func getGithubDiscussions(since time.Time, config *Config) ([]GithubIssueResult, error) {
+	var results []GithubIssueResult
+	cursor := ""
+	for page := 1; page <= githubIssueMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		variables := map[string]interface{}{
+			"query": githubSearchQuery(config, since),
+		}
+		if cursor != "" {
+			variables["cursor"] = cursor
+		}
+
+		var response GithubDiscussionSearchResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetAuthToken(config.GithubToken).
+			SetBody(map[string]interface{}{
+				"query":     githubDiscussionSearchQuery,
+				"variables": variables,
+			}).
+			SetResult(&response).
+			Post("https://api.github.com/graphql")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching github discussions: %s", resp.Status())
+		}
+
+		if len(response.Errors) > 0 {
+			return results, fmt.Errorf("error searching github discussions: %s", response.Errors[0].Message)
+		}
+
+		for _, item := range response.Data.Search.Nodes {
+			// non-discussion nodes are decoded as empty items
+			if item.ID == "" {
+				continue
+			}
+
+			if isIgnoredGithubOwner(item.Repository.NameWithOwner, config) {
+				continue
+			}
+
+			if !config.Matcher.Verify("github_issue", item.ID, item.Title, item.BodyText) {
+				continue
+			}
+
+			state := "open"
+			if item.IsAnswered {
+				state = "answered"
+			} else if item.Closed {
+				state = "closed"
+			}
+
+			labels := []string{}
+			for _, label := range item.Labels.Nodes {
+				labels = append(labels, label.Name)
+			}
+			if item.Category.Name != "" {
+				labels = append(labels, item.Category.Name)
+			}
+
+			results = append(results, GithubIssueResult{
+				NodeID:          item.ID,
+				Kind:            "discussion",
+				Repository:      item.Repository.NameWithOwner,
+				Number:          item.Number,
+				Title:           item.Title,
+				URL:             item.URL,
+				State:           state,
+				Labels:          labels,
+				Comments:        item.Comments.TotalCount,
+				AuthorLogin:     item.Author.Login,
+				AuthorAvatarURL: item.Author.AvatarURL,
+				AuthorURL:       item.Author.URL,
+				CreatedAt:       item.CreatedAt,
+			})
+		}
+
+		if !response.Data.Search.PageInfo.HasNextPage {
+			break
+		}
+		cursor = response.Data.Search.PageInfo.EndCursor
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForGithubIssue(result GithubIssueResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"node_id": result.NodeID,
+		"kind":    result.Kind,
+		"title":   result.Title,
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Repository",
+			Value: result.Repository,
+			Short: true,
+		},
+		{
+			Title: "State",
+			Value: result.State,
+			Short: true,
+		},
+		{
+			Title: "# Comments",
+			Value: strconv.FormatInt(int64(result.Comments), 10),
+			Short: true,
+		},
+	}
+
+	if len(result.Labels) > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Labels",
+			Value: strings.Join(result.Labels, ", "),
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   fmt.Sprintf("New %s on Github!", result.Kind),
+		AuthorName: result.AuthorLogin,
+		AuthorIcon: result.AuthorAvatarURL,
+		AuthorLink: result.AuthorURL,
+		Title:      fmt.Sprintf("%s#%d: %s", result.Repository, result.Number, result.Title),
+		TitleLink:  result.URL,
+		Footer:     "Github Issue Notification",
+		FooterIcon: githubIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.CreatedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":github:"),
+		slack.MsgOptionText(fmt.Sprintf("New %s on <%s|Github>", result.Kind, result.URL), false),
+		slack.MsgOptionUsername("Github Issue Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processGithubIssues(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&GithubIssue{}); err != nil {
+		return fmt.Errorf("error migrating GithubIssue: %w", err)
+	}
+
+	since, err := githubIssueSince(db)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("since", since.UTC().Format(time.RFC3339)).Info("Fetching issues and pull requests")
+	results, err := getGithubIssues(since, config)
+	if err != nil {
+		return err
+	}
+
+	if config.GithubToken == "" {
+		log.Warn("No GITHUB_TOKEN specified, skipping github discussions")
+	} else {
+		log.Info("Fetching discussions")
+		discussions, err := getGithubDiscussions(since, config)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, discussions...)
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("issue_count", len(results)).Info("Processing issues")
+	for _, result := range results {
+		logFields := log.Fields{
+			"node_id": result.NodeID,
+			"kind":    result.Kind,
+			"title":   result.Title,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity GithubIssue
+		if dbResult := db.First(&entity, "node_id = ?", result.NodeID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new issue")
+		entity = GithubIssue{
+			NodeID:   result.NodeID,
+			Kind:     result.Kind,
+			Title:    result.Title,
+			Language: item.Language,
+			OpenedAt: &result.CreatedAt,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting issue into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording issue")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForGithubIssue(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting issue to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for issue")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_issue_count": len(results),
+		"inserted_issue_count":  inserted,
+		"notified_issue_count":  notified,
+	}).Info("Done with github issues")
+
+	return nil
+}
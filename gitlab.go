@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var gitlabIconURL = "https://about.gitlab.com/images/press/logo/png/gitlab-icon-rgb.png"
+
+var gitlabForge = Forge{
+	Service:   "gitlab",
+	Name:      "GitLab",
+	IconURL:   gitlabIconURL,
+	IconEmoji: ":gitlab:",
+}
+
+// gitlabMaxPages caps how many pages are fetched on a single run
+var gitlabMaxPages = 5
+
+type GitlabProjectItem struct {
+	ID                int       `json:"id"`
+	Description       string    `json:"description"`
+	Name              string    `json:"name"`
+	NameWithNamespace string    `json:"name_with_namespace"`
+	Path              string    `json:"path"`
+	PathWithNamespace string    `json:"path_with_namespace"`
+	CreatedAt         time.Time `json:"created_at"`
+	DefaultBranch     string    `json:"default_branch"`
+	WebURL            string    `json:"web_url"`
+	AvatarURL         string    `json:"avatar_url"`
+	StarCount         int       `json:"star_count"`
+	ForksCount        int       `json:"forks_count"`
+	LastActivityAt    time.Time `json:"last_activity_at"`
+	Namespace         struct {
+		ID        int    `json:"id"`
+		Name      string `json:"name"`
+		Path      string `json:"path"`
+		Kind      string `json:"kind"`
+		FullPath  string `json:"full_path"`
+		AvatarURL string `json:"avatar_url"`
+		WebURL    string `json:"web_url"`
+	} `json:"namespace"`
+}
+
+func getGitlabRepositories(config *Config) ([]RepositoryResult, error) {
+	var results []RepositoryResult
+	for page := 1; page <= gitlabMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		var response []GitlabProjectItem
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"search":   config.Tag,
+				"per_page": "100",
+				"page":     strconv.FormatInt(int64(page), 10),
+				"order_by": "created_at",
+				"sort":     "desc",
+			}).
+			SetResult(&response).
+			Get("https://gitlab.com/api/v4/projects")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching gitlab projects: %s", resp.Status())
+		}
+
+		if len(response) == 0 {
+			break
+		}
+
+		for _, project := range response {
+			if !config.Matcher.Verify("gitlab", project.WebURL, project.PathWithNamespace, project.Description) {
+				continue
+			}
+
+			results = append(results, RepositoryResult{
+				Forge:          gitlabForge,
+				Host:           "gitlab.com",
+				RepositoryID:   int64(project.ID),
+				FullName:       project.PathWithNamespace,
+				Description:    project.Description,
+				URL:            project.WebURL,
+				OwnerLogin:     project.Namespace.FullPath,
+				OwnerAvatarURL: project.Namespace.AvatarURL,
+				OwnerURL:       project.Namespace.WebURL,
+				Stars:          project.StarCount,
+				CreatedAt:      project.CreatedAt,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// getGitlabRepositoryLanguage sets the language of the repository to its
+// most used language, as the project listing does not include languages
+func getGitlabRepositoryLanguage(result RepositoryResult) (RepositoryResult, error) {
+	log.WithField("repository_id", result.RepositoryID).Info("Fetching languages")
+	var response map[string]float64
+	client := resty.New()
+	resp, err := client.R().
+		SetResult(&response).
+		Get(fmt.Sprintf("https://gitlab.com/api/v4/projects/%d/languages", result.RepositoryID))
+	if err != nil {
+		return result, err
+	}
+
+	if resp.IsError() {
+		return result, fmt.Errorf("error fetching gitlab project languages: %s", resp.Status())
+	}
+
+	languages := make([]string, 0, len(response))
+	for language := range response {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		return response[languages[i]] > response[languages[j]]
+	})
+
+	if len(languages) > 0 {
+		result.Language = languages[0]
+	}
+
+	return result, nil
+}
+
+func processGitlabRepositories(config *Config, db *gorm.DB) error {
+	log.Info("Fetching repositories")
+	results, err := getGitlabRepositories(config)
+	if err != nil {
+		return err
+	}
+
+	return processForgeRepositories(results, getGitlabRepositoryLanguage, config, db)
+}
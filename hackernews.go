@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -92,3 +95,63 @@ type HackerNewsResult struct {
 		} `json:"url"`
 	} `json:"_highlightResult"`
 }
+
+// FilterItem converts the story or comment for evaluation against the filter
+// rules of the given hacker news service. Only stories share links, as the
+// url of a comment is that of the story it was left on.
+func (r HackerNewsResult) FilterItem(source string) FilterItem {
+	link := r.URL
+	if link == "" {
+		link = r.StoryURL
+	}
+
+	text := strings.Join([]string{r.Title, stripFeedHTML(r.StoryText), stripFeedHTML(r.CommentText)}, " ")
+	item := FilterItem{
+		Source:   source,
+		Text:     text,
+		Author:   r.Author,
+		Language: detectLanguage(text),
+		URL:      link,
+		Metrics: map[string]float64{
+			"points":   float64(r.Points),
+			"comments": float64(r.NumComments),
+		},
+	}
+
+	if source == "hackernews_story" {
+		item.Links = []string{r.URL, fmt.Sprintf("https://news.ycombinator.com/item?id=%s", r.ObjectID)}
+	}
+
+	return item
+}
+
+// HackerNewsStoryType is the kind of submission a story is
+type HackerNewsStoryType struct {
+	Name  string
+	Emoji string
+}
+
+var (
+	hackernewsStoryTypeStory  = HackerNewsStoryType{Name: "Story", Emoji: "📚"}
+	hackernewsStoryTypeAsk    = HackerNewsStoryType{Name: "Ask HN", Emoji: "❓"}
+	hackernewsStoryTypeShow   = HackerNewsStoryType{Name: "Show HN", Emoji: "🎨"}
+	hackernewsStoryTypeLaunch = HackerNewsStoryType{Name: "Launch HN", Emoji: "🚀"}
+	hackernewsStoryTypeJob    = HackerNewsStoryType{Name: "Job", Emoji: "💼"}
+)
+
+// StoryType classifies the story from its algolia tags. Launch HN posts are
+// not tagged separately, so they are detected by their title prefix.
+func (r HackerNewsResult) StoryType() HackerNewsStoryType {
+	switch {
+	case slices.Contains(r.Tags, "job"):
+		return hackernewsStoryTypeJob
+	case strings.HasPrefix(r.Title, "Launch HN:"):
+		return hackernewsStoryTypeLaunch
+	case slices.Contains(r.Tags, "show_hn"):
+		return hackernewsStoryTypeShow
+	case slices.Contains(r.Tags, "ask_hn"):
+		return hackernewsStoryTypeAsk
+	}
+
+	return hackernewsStoryTypeStory
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
@@ -13,9 +12,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// hackernewsCommentExcerptLength caps the length of each comment shown in
+// notifications
+var hackernewsCommentExcerptLength = 300
+
+// hackernewsCommentGroupLimit caps the comments announced in a single
+// message, as slack rejects messages with more than 100 attachments
+var hackernewsCommentGroupLimit = 100
+
 type HackerNewsComment struct {
 	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
 	ObjectID string `gorm:"not null" form:"object_id" json:"object_id"`
+	Language string `form:"language" json:"language"`
 }
 
 func getHackernewsComments(config *Config) ([]HackerNewsResult, error) {
@@ -42,26 +50,10 @@ func getHackernewsComments(config *Config) ([]HackerNewsResult, error) {
 			break
 		}
 
+		// algolia tolerates typos, so make sure the tag is actually mentioned
 		for _, result := range response.Hits {
-			if len(result.HighlightResult.Author.MatchedWords) > 0 {
-				if !strings.Contains(strings.ToLower(result.HighlightResult.Author.Value), config.Tag) {
-					continue
-				}
-			}
-			if len(result.HighlightResult.CommentText.MatchedWords) > 0 {
-				if !strings.Contains(strings.ToLower(result.HighlightResult.CommentText.Value), config.Tag) {
-					continue
-				}
-			}
-			if len(result.HighlightResult.StoryTitle.MatchedWords) > 0 {
-				if !strings.Contains(strings.ToLower(result.HighlightResult.StoryTitle.Value), config.Tag) {
-					continue
-				}
-			}
-			if len(result.HighlightResult.StoryURL.MatchedWords) > 0 {
-				if !strings.Contains(strings.ToLower(result.HighlightResult.StoryURL.Value), config.Tag) {
-					continue
-				}
+			if !config.Matcher.Verify("hackernews_comment", result.ObjectID, stripFeedHTML(result.CommentText), result.StoryTitle, result.StoryURL, result.Author) {
+				continue
 			}
 
 			results = append(results, result)
@@ -71,60 +63,116 @@ func getHackernewsComments(config *Config) ([]HackerNewsResult, error) {
 	return results, nil
 }
 
-func sendSlackNotificationForHackernewsComment(result HackerNewsResult, config *Config) error {
+// HackerNewsCommentGroup holds the comments on a single story, so that they
+// can be announced together
+type HackerNewsCommentGroup struct {
+	StoryID    int
+	StoryTitle string
+	StoryURL   string
+	Comments   []HackerNewsResult
+}
+
+// groupHackernewsComments groups comments by the story they were made on,
+// keeping the order in which each story was first seen. Stories with more
+// comments than fit in a single message are split across several groups.
+func groupHackernewsComments(results []HackerNewsResult) []HackerNewsCommentGroup {
+	var groups []HackerNewsCommentGroup
+	index := map[int]int{}
+	for _, result := range results {
+		i, ok := index[result.StoryID]
+		if !ok || len(groups[i].Comments) >= hackernewsCommentGroupLimit {
+			i = len(groups)
+			index[result.StoryID] = i
+			groups = append(groups, HackerNewsCommentGroup{
+				StoryID:    result.StoryID,
+				StoryTitle: result.StoryTitle,
+				StoryURL:   result.StoryURL,
+			})
+		}
+
+		groups[i].Comments = append(groups[i].Comments, result)
+	}
+
+	return groups
+}
+
+func sendSlackNotificationForHackernewsComments(group HackerNewsCommentGroup, config *Config) error {
 	if !config.NotifySlack {
 		return nil
 	}
 
 	logFields := log.Fields{
-		"comment_id": result.ObjectID,
+		"story_id":      group.StoryID,
+		"comment_count": len(group.Comments),
 	}
 
-	link := fmt.Sprintf("https://news.ycombinator.com/item?id=%s", result.ObjectID)
-	fields := []slack.AttachmentField{
-		{
-			Title: "Type",
-			Value: "✍️",
-			Short: true,
-		},
-	}
+	storyLink := fmt.Sprintf("https://news.ycombinator.com/item?id=%d", group.StoryID)
+	attachments := []slack.Attachment{}
+	for i, result := range group.Comments {
+		link := fmt.Sprintf("https://news.ycombinator.com/item?id=%s", result.ObjectID)
+		parentLink := fmt.Sprintf("https://news.ycombinator.com/item?id=%d", result.ParentID)
+		parentTitle := "Comment"
+		if result.ParentID == result.StoryID {
+			parentTitle = "Story"
+		}
+
+		fields := []slack.AttachmentField{
+			{
+				Title: "Type",
+				Value: "✍️",
+				Short: true,
+			},
+			{
+				Title: "Parent",
+				Value: fmt.Sprintf("<%s|%s>", parentLink, parentTitle),
+				Short: true,
+			},
+		}
+
+		attachment := slack.Attachment{
+			Color:      "#36a64f",
+			Fallback:   "New comment on Hacker News!",
+			AuthorName: result.Author,
+			AuthorLink: fmt.Sprintf("https://news.ycombinator.com/user?id=%s", result.Author),
+			Text:       fmt.Sprintf("%s\n<%s|View comment>", textExcerpt(result.CommentText, hackernewsCommentExcerptLength), link),
+			Footer:     "Hacker News Comment Notification",
+			FooterIcon: hackernewsIconURL,
+			Ts:         json.Number(strconv.FormatInt(int64(result.CreatedAt.Unix()), 10)),
+			Fields:     fields,
+		}
 
-	if len(result.HighlightResult.URL.Value) > 0 {
-		fields = append(fields, slack.AttachmentField{
-			Title: "Original Link",
-			Value: result.HighlightResult.URL.Value,
-			Short: true,
-		})
+		// the story is only shown once, at the top of the message
+		if i == 0 {
+			attachment.Title = group.StoryTitle
+			attachment.TitleLink = storyLink
+			if len(group.StoryURL) > 0 {
+				attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+					Title: "Original Link",
+					Value: group.StoryURL,
+					Short: true,
+				})
+			}
+		}
+
+		attachments = append(attachments, attachment)
 	}
 
-	attachment := slack.Attachment{
-		Color:      "#36a64f",
-		Fallback:   "New comment on Hacker News!",
-		AuthorName: result.Author,
-		AuthorLink: fmt.Sprintf("https://news.ycombinator.com/user?id=%s", result.Author),
-		TitleLink:  link,
-		Footer:     "Hacker News Comment Notification",
-		FooterIcon: hackernewsIconURL,
-		Ts:         json.Number(strconv.FormatInt(int64(result.CreatedAt.Unix()), 10)),
-		Fields:     fields,
+	text := "New comment on <" + storyLink + "|Hacker News>"
+	if len(group.Comments) > 1 {
+		text = fmt.Sprintf("%d new comments on <%s|Hacker News>", len(group.Comments), storyLink)
 	}
 
 	log.WithFields(logFields).Info("Notifying slack")
 	messageOpts := []slack.MsgOption{
 		slack.MsgOptionAsUser(false),
-		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionAttachments(attachments...),
 		slack.MsgOptionIconEmoji(":hacker-news:"),
-		slack.MsgOptionText("New comment on <"+link+"|Hacker News>", false),
+		slack.MsgOptionText(text, false),
 		slack.MsgOptionUsername("Hacker News Comment Notifications"),
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
-	}
-
-	return nil
+	return postSlackMessage(config, messageOpts...)
 }
 
 func processHackernewsComments(config *Config, db *gorm.DB) error {
@@ -140,12 +188,23 @@ func processHackernewsComments(config *Config, db *gorm.DB) error {
 
 	inserted := 0
 	notified := 0
+	// comments are grouped per channel, as low scoring comments may be sent
+	// to the review channel
+	var channels []string
+	notifyConfigs := map[string]*Config{}
+	mentionConfigs := map[string]*Config{}
+	newResults := map[string][]HackerNewsResult{}
 	log.WithField("comment_count", len(results)).Info("Processing comments")
 	for _, result := range results {
 		logFields := log.Fields{
 			"comment_object_id": result.ObjectID,
 		}
 
+		item := result.FilterItem("hackernews_comment")
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
 		var entity HackerNewsComment
 		if dbResult := db.First(&entity, "object_id = ?", result.ObjectID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
 			continue
@@ -154,6 +213,7 @@ func processHackernewsComments(config *Config, db *gorm.DB) error {
 		log.WithFields(logFields).Info("Inserting new comment")
 		entity = HackerNewsComment{
 			ObjectID: result.ObjectID,
+			Language: item.Language,
 		}
 
 		if dbResult := db.Create(&entity); dbResult.Error != nil {
@@ -162,12 +222,53 @@ func processHackernewsComments(config *Config, db *gorm.DB) error {
 		}
 
 		inserted += 1
-		if err := sendSlackNotificationForHackernewsComment(result, config); err != nil {
-			log.WithError(err).WithFields(logFields).Fatal("error posting comment to slack")
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording comment")
+			continue
+		}
+		if notifyConfig == nil {
 			continue
 		}
 
-		notified += 1
+		// comments are grouped into a single message per story, so are
+		// never posted to the thread of a near-duplicate
+		channel := notifyConfig.SlackChannelID
+		if _, ok := notifyConfigs[channel]; !ok {
+			channels = append(channels, channel)
+			notifyConfigs[channel] = notifyConfig
+		}
+		mentionConfigs[result.ObjectID] = notifyConfig
+		newResults[channel] = append(newResults[channel], result)
+	}
+
+	for _, channel := range channels {
+		for _, group := range groupHackernewsComments(newResults[channel]) {
+			logFields := log.Fields{
+				"story_id":      group.StoryID,
+				"comment_count": len(group.Comments),
+			}
+
+			groupConfig := *notifyConfigs[channel]
+			groupConfig.SlackThread = &SlackThread{}
+			if err := sendSlackNotificationForHackernewsComments(group, &groupConfig); err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error posting comments to slack")
+				continue
+			}
+
+			// every comment in the group is recorded against the message
+			// the group was posted as
+			for _, result := range group.Comments {
+				mentionConfig := mentionConfigs[result.ObjectID]
+				mentionConfig.SlackThread.ChannelID = groupConfig.SlackThread.ChannelID
+				mentionConfig.SlackThread.TS = groupConfig.SlackThread.TS
+				if err := recordNotification(mentionConfig, db); err != nil {
+					log.WithError(err).WithFields(logFields).Fatal("error recording notification")
+				}
+			}
+
+			notified += len(group.Comments)
+		}
 	}
 	log.WithFields(log.Fields{
 		"processed_comment_count": len(results),
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+// hackernewsHiringThreads is how many of the most recent monthly hiring
+// threads are scanned, as companies keep posting to a thread for weeks
+var hackernewsHiringThreads = 2
+
+// hackernewsHiringExcerptLength caps the length of the posting shown in
+// notifications
+var hackernewsHiringExcerptLength = 500
+
+type HackerNewsHiringComment struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	ObjectID string `gorm:"not null" form:"object_id" json:"object_id"`
+	StoryID  int    `gorm:"not null" form:"story_id" json:"story_id"`
+	Company  string `form:"company" json:"company"`
+	Language string `form:"language" json:"language"`
+}
+
+// Company returns the company name from a hiring post, which by convention
+// is the first segment of the pipe-separated header line
+func (r HackerNewsResult) Company() string {
+	text := stripFeedHTML(strings.ReplaceAll(r.CommentText, "<p>", "\n"))
+	header, _, _ := strings.Cut(text, "\n")
+	company, _, _ := strings.Cut(header, "|")
+	return strings.TrimSpace(company)
+}
+
+// getHackernewsHiringThreads fetches the most recent "Who is hiring?" threads,
+// which are posted monthly by the whoishiring account
+func getHackernewsHiringThreads() ([]HackerNewsResult, error) {
+	var results []HackerNewsResult
+	var response HackerNewsResponse
+	client := resty.New()
+	_, err := client.R().
+		SetQueryParams(map[string]string{
+			"tags":        "story,author_whoishiring",
+			"hitsPerPage": "10",
+		}).
+		SetResult(&response).
+		Get("http://hn.algolia.com/api/v1/search_by_date")
+	if err != nil {
+		return results, err
+	}
+
+	// the account also posts "Who wants to be hired?" and freelancer threads
+	for _, result := range response.Hits {
+		if !strings.Contains(strings.ToLower(result.Title), "who is hiring?") {
+			continue
+		}
+
+		results = append(results, result)
+		if len(results) == hackernewsHiringThreads {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func getHackernewsHiringComments(thread HackerNewsResult, config *Config) ([]HackerNewsResult, error) {
+	var results []HackerNewsResult
+	page := 0
+	for {
+		log.WithFields(log.Fields{
+			"story_id": thread.ObjectID,
+			"page":     page,
+		}).Info("Fetching page")
+		var response HackerNewsResponse
+		client := resty.New()
+		_, err := client.R().
+			SetQueryParams(map[string]string{
+				"query": config.Tag,
+				"tags":  fmt.Sprintf("comment,story_%s", thread.ObjectID),
+				"page":  strconv.FormatInt(int64(page), 10),
+			}).
+			SetResult(&response).
+			Get("http://hn.algolia.com/api/v1/search_by_date")
+		if err != nil {
+			return results, err
+		}
+
+		page += 1
+		if len(response.Hits) == 0 {
+			break
+		}
+
+		for _, result := range response.Hits {
+			if !config.Matcher.Verify("hackernews_hiring", result.ObjectID, stripFeedHTML(result.CommentText)) {
+				continue
+			}
+
+			// replies to postings are discussion rather than postings
+			if strconv.FormatInt(int64(result.ParentID), 10) != thread.ObjectID {
+				continue
+			}
+
+			results = append(results, result)
+		}
+
+		if page >= response.NbPages {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForHackernewsHiringComment(result HackerNewsResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"comment_id": result.ObjectID,
+		"story_id":   result.StoryID,
+	}
+
+	link := fmt.Sprintf("https://news.ycombinator.com/item?id=%s", result.ObjectID)
+	threadLink := fmt.Sprintf("https://news.ycombinator.com/item?id=%d", result.StoryID)
+	company := result.Company()
+	if company == "" {
+		company = result.Author
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Type",
+			Value: hackernewsStoryTypeJob.Emoji + " Who is hiring?",
+			Short: true,
+		},
+		{
+			Title: "Thread",
+			Value: fmt.Sprintf("<%s|%s>", threadLink, result.StoryTitle),
+			Short: true,
+		},
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New hiring post on Hacker News!",
+		AuthorName: result.Author,
+		AuthorLink: fmt.Sprintf("https://news.ycombinator.com/user?id=%s", result.Author),
+		Title:      company,
+		TitleLink:  link,
+		Text:       textExcerpt(result.CommentText, hackernewsHiringExcerptLength),
+		Footer:     "Hacker News Hiring Notification",
+		FooterIcon: hackernewsIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.CreatedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":hacker-news:"),
+		slack.MsgOptionText("New hiring post on <"+link+"|Hacker News>", false),
+		slack.MsgOptionUsername("Hacker News Hiring Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processHackernewsHiring(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&HackerNewsHiringComment{}); err != nil {
+		return fmt.Errorf("error migrating HackerNewsHiringComment: %w", err)
+	}
+
+	log.Info("Fetching hiring threads")
+	threads, err := getHackernewsHiringThreads()
+	if err != nil {
+		return err
+	}
+
+	var results []HackerNewsResult
+	for _, thread := range threads {
+		log.WithFields(log.Fields{
+			"story_id": thread.ObjectID,
+			"title":    thread.Title,
+		}).Info("Fetching hiring comments")
+		threadResults, err := getHackernewsHiringComments(thread, config)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, threadResults...)
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("comment_count", len(results)).Info("Processing hiring comments")
+	for _, result := range results {
+		logFields := log.Fields{
+			"comment_object_id": result.ObjectID,
+			"story_id":          result.StoryID,
+		}
+
+		item := result.FilterItem("hackernews_hiring")
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity HackerNewsHiringComment
+		if dbResult := db.First(&entity, "object_id = ?", result.ObjectID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new hiring comment")
+		entity = HackerNewsHiringComment{
+			ObjectID: result.ObjectID,
+			StoryID:  result.StoryID,
+			Company:  result.Company(),
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting hiring comment into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording hiring comment")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForHackernewsHiringComment(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting hiring comment to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for hiring comment")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_comment_count": len(results),
+		"inserted_comment_count":  inserted,
+		"notified_comment_count":  notified,
+	}).Info("Done with hacker news hiring comments")
+
+	return nil
+}
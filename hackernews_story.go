@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
@@ -17,6 +16,7 @@ type HackerNewsStory struct {
 	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
 	ObjectID string `gorm:"not null" form:"object_id" json:"object_id"`
 	Title    string `gorm:"not null" form:"title" json:"title"`
+	Language string `form:"language" json:"language"`
 }
 
 func getHackernewsStories(config *Config) ([]HackerNewsResult, error) {
@@ -29,7 +29,7 @@ func getHackernewsStories(config *Config) ([]HackerNewsResult, error) {
 		_, err := client.R().
 			SetQueryParams(map[string]string{
 				"query": config.Tag,
-				"tags":  "story",
+				"tags":  "(story,job)",
 				"page":  strconv.FormatInt(int64(page), 10),
 			}).
 			SetResult(&response).
@@ -43,16 +43,10 @@ func getHackernewsStories(config *Config) ([]HackerNewsResult, error) {
 			break
 		}
 
+		// algolia tolerates typos, so make sure the tag is actually mentioned
 		for _, result := range response.Hits {
-			if len(result.HighlightResult.URL.MatchedWords) > 0 {
-				if !strings.Contains(strings.ToLower(result.HighlightResult.URL.Value), config.Tag) {
-					continue
-				}
-			}
-			if len(result.HighlightResult.Author.MatchedWords) > 0 {
-				if !strings.Contains(strings.ToLower(result.HighlightResult.Author.Value), config.Tag) {
-					continue
-				}
+			if !config.Matcher.Verify("hackernews_story", result.ObjectID, result.Title, result.URL, stripFeedHTML(result.StoryText), result.Author) {
+				continue
 			}
 
 			results = append(results, result)
@@ -72,6 +66,7 @@ func sendSlackNotificationForHackernewsStory(result HackerNewsResult, config *Co
 		"title":    result.Title,
 	}
 
+	storyType := result.StoryType()
 	link := fmt.Sprintf("https://news.ycombinator.com/item?id=%s", result.ObjectID)
 	fields := []slack.AttachmentField{
 		{
@@ -86,7 +81,7 @@ func sendSlackNotificationForHackernewsStory(result HackerNewsResult, config *Co
 		},
 		{
 			Title: "Type",
-			Value: "📚",
+			Value: fmt.Sprintf("%s %s", storyType.Emoji, storyType.Name),
 			Short: true,
 		},
 	}
@@ -122,12 +117,7 @@ func sendSlackNotificationForHackernewsStory(result HackerNewsResult, config *Co
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
-	}
-
-	return nil
+	return postSlackMessage(config, messageOpts...)
 }
 
 func processHackernewsStories(config *Config, db *gorm.DB) error {
@@ -150,6 +140,11 @@ func processHackernewsStories(config *Config, db *gorm.DB) error {
 			"title":           result.Title,
 		}
 
+		item := result.FilterItem("hackernews_story")
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
 		var entity HackerNewsStory
 		if dbResult := db.First(&entity, "object_id = ?", result.ObjectID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
 			continue
@@ -159,6 +154,7 @@ func processHackernewsStories(config *Config, db *gorm.DB) error {
 		entity = HackerNewsStory{
 			ObjectID: result.ObjectID,
 			Title:    result.Title,
+			Language: item.Language,
 		}
 
 		if dbResult := db.Create(&entity); dbResult.Error != nil {
@@ -167,11 +163,25 @@ func processHackernewsStories(config *Config, db *gorm.DB) error {
 		}
 
 		inserted += 1
-		if err := sendSlackNotificationForHackernewsStory(result, config); err != nil {
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording story")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForHackernewsStory(result, notifyConfig); err != nil {
 			log.WithError(err).WithFields(logFields).Fatal("error posting story to slack")
 			continue
 		}
 
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for story")
+			continue
+		}
+
 		notified += 1
 	}
 	log.WithFields(log.Fields{
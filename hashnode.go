@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var hashnodeIconURL = "https://hashnode.com/favicon.ico"
+
+// hashnodeMaxPages caps how many pages are fetched on a single run
+var hashnodeMaxPages = 5
+
+var hashnodeTagFeedQuery = `query TagFeed($slug: String!, $first: Int!, $after: String) {
+  tag(slug: $slug) {
+    posts(first: $first, after: $after, filter: {sortBy: recent}) {
+      edges {
+        node {
+          id
+          title
+          brief
+          url
+          readTimeInMinutes
+          publishedAt
+          tags {
+            name
+          }
+          author {
+            username
+            name
+            profilePicture
+          }
+          publication {
+            title
+            url
+          }
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+}`
+
+type HashnodeArticle struct {
+	ID        int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	ArticleID string `gorm:"not null" form:"article_id" json:"article_id"`
+	Title     string `gorm:"not null" form:"title" json:"title"`
+	Language  string `form:"language" json:"language"`
+}
+
+type HashnodeResponse struct {
+	Data struct {
+		Tag *struct {
+			Posts struct {
+				Edges []struct {
+					Node HashnodeArticleResult `json:"node"`
+				} `json:"edges"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"posts"`
+		} `json:"tag"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type HashnodeArticleResult struct {
+	ID                string    `json:"id"`
+	Title             string    `json:"title"`
+	Brief             string    `json:"brief"`
+	URL               string    `json:"url"`
+	ReadTimeInMinutes int       `json:"readTimeInMinutes"`
+	PublishedAt       time.Time `json:"publishedAt"`
+	Tags              []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	Author struct {
+		Username       string `json:"username"`
+		Name           string `json:"name"`
+		ProfilePicture string `json:"profilePicture"`
+	} `json:"author"`
+	Publication struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"publication"`
+}
+
+// FilterItem converts the article for evaluation against the filter rules
+func (r HashnodeArticleResult) FilterItem() FilterItem {
+	text := strings.Join([]string{r.Title, r.Brief}, " ")
+	return FilterItem{
+		Source:   "hashnode",
+		Text:     text,
+		Author:   r.Author.Username,
+		Language: detectLanguage(text),
+		URL:      r.URL,
+		Links:    []string{r.URL},
+	}
+}
+
+func getHashnodeArticles(config *Config) ([]HashnodeArticleResult, error) {
+	var results []HashnodeArticleResult
+	after := ""
+	for page := 1; page <= hashnodeMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		variables := map[string]interface{}{
+			"slug":  strings.ToLower(config.Tag),
+			"first": 20,
+		}
+		if after != "" {
+			variables["after"] = after
+		}
+
+		var response HashnodeResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetBody(map[string]interface{}{
+				"query":     hashnodeTagFeedQuery,
+				"variables": variables,
+			}).
+			SetResult(&response).
+			Post("https://gql.hashnode.com")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error fetching hashnode articles: %s", resp.Status())
+		}
+
+		if len(response.Errors) > 0 {
+			return results, fmt.Errorf("error fetching hashnode articles: %s", response.Errors[0].Message)
+		}
+
+		// the tag does not exist on hashnode
+		if response.Data.Tag == nil {
+			break
+		}
+
+		for _, edge := range response.Data.Tag.Posts.Edges {
+			values := []string{edge.Node.Title, edge.Node.Brief}
+			for _, tag := range edge.Node.Tags {
+				values = append(values, tag.Name)
+			}
+			if !config.Matcher.Verify("hashnode", edge.Node.ID, values...) {
+				continue
+			}
+
+			results = append(results, edge.Node)
+		}
+
+		if !response.Data.Tag.Posts.PageInfo.HasNextPage {
+			break
+		}
+		after = response.Data.Tag.Posts.PageInfo.EndCursor
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForHashnodeArticle(result HashnodeArticleResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"article_id": result.ID,
+		"title":      result.Title,
+	}
+
+	fields := []slack.AttachmentField{}
+	if result.Publication.Title != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Publication",
+			Value: fmt.Sprintf("<%s|%s>", result.Publication.URL, result.Publication.Title),
+			Short: true,
+		})
+	}
+
+	if result.ReadTimeInMinutes > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Reading Time",
+			Value: fmt.Sprintf("%d min", result.ReadTimeInMinutes),
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New article on Hashnode!",
+		AuthorName: result.Author.Username,
+		AuthorLink: fmt.Sprintf("https://hashnode.com/@%s", result.Author.Username),
+		AuthorIcon: result.Author.ProfilePicture,
+		Title:      result.Title,
+		TitleLink:  result.URL,
+		Text:       result.Brief,
+		Footer:     "Hashnode Article Notification",
+		FooterIcon: hashnodeIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.PublishedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":hashnode:"),
+		slack.MsgOptionText("New article on <"+result.URL+"|Hashnode>", false),
+		slack.MsgOptionUsername("Hashnode Article Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processHashnodeArticles(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&HashnodeArticle{}); err != nil {
+		return fmt.Errorf("error migrating HashnodeArticle: %w", err)
+	}
+
+	log.Info("Fetching articles")
+	results, err := getHashnodeArticles(config)
+	if err != nil {
+		return err
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("article_count", len(results)).Info("Processing articles")
+	for _, result := range results {
+		logFields := log.Fields{
+			"article_id": result.ID,
+			"title":      result.Title,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity HashnodeArticle
+		if dbResult := db.First(&entity, "article_id = ?", result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new article")
+		entity = HashnodeArticle{
+			ArticleID: result.ID,
+			Title:     result.Title,
+			Language:  item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting article into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording article")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForHashnodeArticle(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting article to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for article")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_article_count": len(results),
+		"inserted_article_count":  inserted,
+		"notified_article_count":  notified,
+	}).Info("Done with hashnode articles")
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/abadojack/whatlanggo"
+)
+
+// languageMinLength is the shortest text a language is detected for, as
+// trigram detection is little better than guessing on a handful of words
+var languageMinLength = 20
+
+// languageMinConfidence is the confidence below which a detected language is
+// discarded. This is lower than the threshold the detector considers
+// reliable, which rejects too many short posts that are plainly english.
+var languageMinConfidence = 0.5
+
+// detectLanguage returns the ISO 639-1 code of the language the text is
+// written in, falling back to the ISO 639-3 code for languages without one.
+// Detection runs entirely offline using trigram profiles, and an empty
+// string is returned when the text is too short or the result unreliable.
+func detectLanguage(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) < languageMinLength {
+		return ""
+	}
+
+	info := whatlanggo.Detect(text)
+	if info.Confidence < languageMinConfidence {
+		return ""
+	}
+
+	if code := info.Lang.Iso6391(); code != "" {
+		return code
+	}
+
+	return info.Lang.Iso6393()
+}
+
+// itemLanguage returns the primary subtag of the language reported by a
+// source, such as en for en-US, and detects the language from the text when
+// the source does not report one or reports it as undetermined
+func itemLanguage(reported string, text string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(reported), "-")
+	if len(primary) == 2 {
+		return primary
+	}
+
+	return detectLanguage(text)
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var lemmyIconURL = "https://lemmy.ml/static/assets/icons/apple-touch-icon.png"
+
+// lemmyMaxPages caps how many pages are fetched per instance, community and
+// result type on a single run
+var lemmyMaxPages = 5
+
+type LemmyItem struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	ApID     string `gorm:"not null" form:"ap_id" json:"ap_id"`
+	Type     string `gorm:"not null" form:"type" json:"type"`
+	Title    string `gorm:"not null" form:"title" json:"title"`
+	Language string `form:"language" json:"language"`
+}
+
+type LemmySearchResponse struct {
+	Type        string             `json:"type_"`
+	Comments    []LemmyCommentView `json:"comments"`
+	Posts       []LemmyPostView    `json:"posts"`
+	Communities []interface{}      `json:"communities"`
+	Users       []interface{}      `json:"users"`
+}
+
+type LemmyPerson struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Avatar      string `json:"avatar"`
+	ActorID     string `json:"actor_id"`
+	Local       bool   `json:"local"`
+	BotAccount  bool   `json:"bot_account"`
+}
+
+type LemmyCommunity struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	ActorID string `json:"actor_id"`
+	Local   bool   `json:"local"`
+	Icon    string `json:"icon"`
+}
+
+type LemmyPost struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Body        string `json:"body"`
+	CreatorID   int    `json:"creator_id"`
+	CommunityID int    `json:"community_id"`
+	Removed     bool   `json:"removed"`
+	Deleted     bool   `json:"deleted"`
+	NSFW        bool   `json:"nsfw"`
+	Published   string `json:"published"`
+	ApID        string `json:"ap_id"`
+	Local       bool   `json:"local"`
+}
+
+type LemmyPostView struct {
+	Post      LemmyPost      `json:"post"`
+	Creator   LemmyPerson    `json:"creator"`
+	Community LemmyCommunity `json:"community"`
+	Counts    struct {
+		Comments  int `json:"comments"`
+		Score     int `json:"score"`
+		Upvotes   int `json:"upvotes"`
+		Downvotes int `json:"downvotes"`
+	} `json:"counts"`
+}
+
+type LemmyCommentView struct {
+	Comment struct {
+		ID        int    `json:"id"`
+		CreatorID int    `json:"creator_id"`
+		PostID    int    `json:"post_id"`
+		Content   string `json:"content"`
+		Removed   bool   `json:"removed"`
+		Deleted   bool   `json:"deleted"`
+		Published string `json:"published"`
+		ApID      string `json:"ap_id"`
+		Local     bool   `json:"local"`
+		Path      string `json:"path"`
+	} `json:"comment"`
+	Creator   LemmyPerson    `json:"creator"`
+	Post      LemmyPost      `json:"post"`
+	Community LemmyCommunity `json:"community"`
+	Counts    struct {
+		Score      int `json:"score"`
+		Upvotes    int `json:"upvotes"`
+		Downvotes  int `json:"downvotes"`
+		ChildCount int `json:"child_count"`
+	} `json:"counts"`
+}
+
+// FilterItem converts the post for evaluation against the filter rules, where
+// the domain is that of the link being shared if there is one
+func (r LemmyPostView) FilterItem() FilterItem {
+	link := r.Post.URL
+	if link == "" {
+		link = r.Post.ApID
+	}
+
+	text := strings.Join([]string{r.Post.Name, r.Post.Body}, " ")
+	return FilterItem{
+		Source:   "lemmy",
+		Text:     text,
+		Author:   r.Creator.Name,
+		Language: detectLanguage(text),
+		URL:      link,
+		Links:    []string{link, r.Post.ApID},
+		Metrics: map[string]float64{
+			"comments": float64(r.Counts.Comments),
+			"score":    float64(r.Counts.Score),
+		},
+	}
+}
+
+// FilterItem converts the comment for evaluation against the filter rules
+func (r LemmyCommentView) FilterItem() FilterItem {
+	return FilterItem{
+		Source:   "lemmy",
+		Text:     r.Comment.Content,
+		Author:   r.Creator.Name,
+		Language: detectLanguage(r.Comment.Content),
+		URL:      r.Comment.ApID,
+		Metrics: map[string]float64{
+			"replies": float64(r.Counts.ChildCount),
+			"score":   float64(r.Counts.Score),
+		},
+	}
+}
+
+// parseLemmyTime parses the published timestamps lemmy returns, which
+// older instances send without a timezone
+func parseLemmyTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// lemmyCommunityName returns the community@instance name for a community
+func lemmyCommunityName(community LemmyCommunity) string {
+	u, err := url.Parse(community.ActorID)
+	if err != nil || u.Host == "" {
+		return community.Name
+	}
+
+	return fmt.Sprintf("%s@%s", community.Name, u.Host)
+}
+
+func searchLemmy(instance string, community string, searchType string, config *Config) (LemmySearchResponse, error) {
+	var results LemmySearchResponse
+	for page := 1; page <= lemmyMaxPages; page++ {
+		log.WithFields(log.Fields{
+			"instance":  instanceHost(instance),
+			"community": community,
+			"type":      searchType,
+			"page":      page,
+		}).Info("Fetching page")
+
+		var response LemmySearchResponse
+		client := resty.New()
+		request := client.R().
+			SetQueryParams(map[string]string{
+				"q":            config.Tag,
+				"type_":        searchType,
+				"sort":         "New",
+				"listing_type": "All",
+				"limit":        "50",
+				"page":         strconv.FormatInt(int64(page), 10),
+			}).
+			SetResult(&response)
+		if community != "" {
+			request.SetQueryParam("community_name", community)
+		}
+
+		resp, err := request.Get(fmt.Sprintf("%s/api/v3/search", instanceURL(instance)))
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching lemmy on %s: %s", instanceHost(instance), resp.Status())
+		}
+
+		if len(response.Posts) == 0 && len(response.Comments) == 0 {
+			break
+		}
+
+		results.Posts = append(results.Posts, response.Posts...)
+		results.Comments = append(results.Comments, response.Comments...)
+	}
+
+	return results, nil
+}
+
+func getLemmyResults(config *Config) ([]LemmyPostView, []LemmyCommentView, error) {
+	var posts []LemmyPostView
+	var comments []LemmyCommentView
+
+	communities := config.LemmyCommunities
+	if len(communities) == 0 {
+		communities = []string{""}
+	}
+
+	for _, instance := range config.LemmyInstances {
+		for _, community := range communities {
+			for _, searchType := range []string{"Posts", "Comments"} {
+				response, err := searchLemmy(instance, community, searchType, config)
+				if err != nil {
+					return posts, comments, err
+				}
+
+				for _, post := range response.Posts {
+					if post.Post.Removed || post.Post.Deleted {
+						continue
+					}
+					if !config.Matcher.Verify("lemmy", post.Post.ApID, post.Post.Name, post.Post.Body, post.Post.URL) {
+						continue
+					}
+					posts = append(posts, post)
+				}
+
+				for _, comment := range response.Comments {
+					if comment.Comment.Removed || comment.Comment.Deleted {
+						continue
+					}
+					if !config.Matcher.Verify("lemmy", comment.Comment.ApID, comment.Comment.Content) {
+						continue
+					}
+					comments = append(comments, comment)
+				}
+			}
+		}
+	}
+
+	return posts, comments, nil
+}
+
+func sendSlackNotificationForLemmyPost(result LemmyPostView, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"ap_id": result.Post.ApID,
+		"title": result.Post.Name,
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Community",
+			Value: lemmyCommunityName(result.Community),
+			Short: true,
+		},
+		{
+			Title: "# Score",
+			Value: strconv.FormatInt(int64(result.Counts.Score), 10),
+			Short: true,
+		},
+		{
+			Title: "# Comments",
+			Value: strconv.FormatInt(int64(result.Counts.Comments), 10),
+			Short: true,
+		},
+	}
+
+	if len(result.Post.URL) > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Original Link",
+			Value: result.Post.URL,
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New post on Lemmy!",
+		AuthorName: result.Creator.Name,
+		AuthorIcon: result.Creator.Avatar,
+		AuthorLink: result.Creator.ActorID,
+		Title:      result.Post.Name,
+		TitleLink:  result.Post.ApID,
+		Text:       result.Post.Body,
+		MarkdownIn: []string{"text"},
+		Footer:     "Lemmy Post Notification",
+		FooterIcon: lemmyIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(parseLemmyTime(result.Post.Published).Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":lemmy:"),
+		slack.MsgOptionText("New post on <"+result.Post.ApID+"|Lemmy>", false),
+		slack.MsgOptionUsername("Lemmy Post Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func sendSlackNotificationForLemmyComment(result LemmyCommentView, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"ap_id": result.Comment.ApID,
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Community",
+			Value: lemmyCommunityName(result.Community),
+			Short: true,
+		},
+		{
+			Title: "# Score",
+			Value: strconv.FormatInt(int64(result.Counts.Score), 10),
+			Short: true,
+		},
+		{
+			Title: "# Replies",
+			Value: strconv.FormatInt(int64(result.Counts.ChildCount), 10),
+			Short: true,
+		},
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New comment on Lemmy!",
+		AuthorName: result.Creator.Name,
+		AuthorIcon: result.Creator.Avatar,
+		AuthorLink: result.Creator.ActorID,
+		Title:      result.Post.Name,
+		TitleLink:  result.Comment.ApID,
+		Text:       result.Comment.Content,
+		MarkdownIn: []string{"text"},
+		Footer:     "Lemmy Comment Notification",
+		FooterIcon: lemmyIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(parseLemmyTime(result.Comment.Published).Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":lemmy:"),
+		slack.MsgOptionText("New comment on <"+result.Comment.ApID+"|Lemmy>", false),
+		slack.MsgOptionUsername("Lemmy Comment Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processLemmy(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&LemmyItem{}); err != nil {
+		return fmt.Errorf("error migrating LemmyItem: %w", err)
+	}
+
+	log.Info("Fetching posts and comments")
+	posts, comments, err := getLemmyResults(config)
+	if err != nil {
+		return err
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("post_count", len(posts)).Info("Processing posts")
+	for _, result := range posts {
+		logFields := log.Fields{
+			"ap_id": result.Post.ApID,
+			"title": result.Post.Name,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		// federated copies of a post share the ap_id of the original
+		var entity LemmyItem
+		if dbResult := db.First(&entity, "ap_id = ?", result.Post.ApID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new post")
+		entity = LemmyItem{
+			ApID:     result.Post.ApID,
+			Type:     "post",
+			Title:    result.Post.Name,
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting post into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording post")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForLemmyPost(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting post to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for post")
+			continue
+		}
+
+		notified += 1
+	}
+
+	log.WithField("comment_count", len(comments)).Info("Processing comments")
+	for _, result := range comments {
+		logFields := log.Fields{
+			"ap_id": result.Comment.ApID,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity LemmyItem
+		if dbResult := db.First(&entity, "ap_id = ?", result.Comment.ApID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new comment")
+		entity = LemmyItem{
+			ApID:     result.Comment.ApID,
+			Type:     "comment",
+			Title:    result.Post.Name,
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting comment into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording comment")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForLemmyComment(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting comment to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for comment")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_item_count": len(posts) + len(comments),
+		"inserted_item_count":  inserted,
+		"notified_item_count":  notified,
+	}).Info("Done with lemmy posts and comments")
+
+	return nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// linkPattern finds urls in plain text, for sources that do not report the
+// links a post shares separately from its content
+var linkPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]]+`)
+
+// linkShorteners are hosts whose links redirect to the url being shared, and
+// which are resolved before canonicalizing
+var linkShorteners = []string{
+	"bit.ly",
+	"buff.ly",
+	"dlvr.it",
+	"goo.gl",
+	"ift.tt",
+	"is.gd",
+	"lnkd.in",
+	"ow.ly",
+	"rebrand.ly",
+	"redd.it",
+	"t.co",
+	"tinyurl.com",
+	"trib.al",
+}
+
+// linkHostAliases maps mobile and alternate hosts to the host they mirror
+var linkHostAliases = map[string]string{
+	"m.facebook.com":     "facebook.com",
+	"m.reddit.com":       "reddit.com",
+	"m.youtube.com":      "youtube.com",
+	"mobile.twitter.com": "x.com",
+	"mobile.x.com":       "x.com",
+	"new.reddit.com":     "reddit.com",
+	"np.reddit.com":      "reddit.com",
+	"old.reddit.com":     "reddit.com",
+	"twitter.com":        "x.com",
+}
+
+// linkTrackingParams are query parameters added by ad and email platforms
+// that never change the page being linked to, and are removed from every
+// url. Parameters starting with utm_ are always removed as well.
+var linkTrackingParams = []string{
+	"_hsenc",
+	"_hsmi",
+	"fbclid",
+	"gclid",
+	"igshid",
+	"mc_cid",
+	"mc_eid",
+	"mkt_tok",
+	"msclkid",
+	"yclid",
+}
+
+// linkHostTrackingParams are tracking parameters that are only removed for
+// the given host, as names such as ref or source select a branch or page
+// elsewhere
+var linkHostTrackingParams = map[string][]string{
+	"medium.com":       {"source"},
+	"open.spotify.com": {"si"},
+	"producthunt.com":  {"ref"},
+	"reddit.com":       {"share_id"},
+	"x.com":            {"ref_src", "ref_url", "s", "t"},
+	"youtube.com":      {"feature", "si", "t"},
+}
+
+// linkResolveTimeout bounds how long resolving a short link may take, as
+// every short link in a run is resolved one after the other
+var linkResolveTimeout = 3 * time.Second
+
+// linkResolveCacheSize caps how many resolved short links are kept
+var linkResolveCacheSize = 1000
+
+// resolvedLinks caches short links resolved during this run, including those
+// that failed to resolve, so that a slow shortener is only waited on once
+var resolvedLinks = map[string]string{}
+
+// Link is a canonical url shared by one or more mentions, along with the
+// slack thread that the first notification for it started. Later mentions
+// of the url are posted as replies to that thread.
+type Link struct {
+	ID              int32     `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	URL             string    `gorm:"not null;index" form:"url" json:"url"`
+	SlackChannelID  string    `gorm:"not null" form:"slack_channel_id" json:"slack_channel_id"`
+	Source          string    `form:"source" json:"source"`
+	ThreadChannelID string    `form:"thread_channel_id" json:"thread_channel_id"`
+	ThreadTS        string    `form:"thread_ts" json:"thread_ts"`
+	ThreadedAt      time.Time `form:"threaded_at" json:"threaded_at"`
+	CreatedAt       time.Time `form:"created_at" json:"created_at"`
+}
+
+// LinkSighting records a mention that shared a link
+type LinkSighting struct {
+	ID        int32     `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	LinkID    int32     `gorm:"not null;index" form:"link_id" json:"link_id"`
+	MentionID int32     `form:"mention_id" json:"mention_id"`
+	Source    string    `gorm:"not null" form:"source" json:"source"`
+	CreatedAt time.Time `form:"created_at" json:"created_at"`
+}
+
+// migrateLinks creates the tables that links are tracked in
+func migrateLinks(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Link{}, &LinkSighting{}); err != nil {
+		return fmt.Errorf("error migrating Link: %w", err)
+	}
+
+	return nil
+}
+
+// textLinks returns the urls found in the text
+func textLinks(text string) []string {
+	var links []string
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		links = append(links, strings.TrimRight(link, ".,;:!?"))
+	}
+
+	return links
+}
+
+// parseLink parses an absolute http or https url
+func parseLink(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("not an http url: %q", raw)
+	}
+
+	return u, nil
+}
+
+// linkHost returns the lowercased hostname without a leading www
+func linkHost(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// resolveShortLink follows the redirects of a short link to the url it
+// points at. Links that fail to resolve are cached as themselves.
+func resolveShortLink(raw string) (string, error) {
+	if resolved, ok := resolvedLinks[raw]; ok {
+		return resolved, nil
+	}
+
+	resolved := raw
+	client := resty.New().SetTimeout(linkResolveTimeout)
+	resp, err := client.R().Head(raw)
+	if err == nil {
+		resolved = resp.RawResponse.Request.URL.String()
+	}
+
+	// evict an arbitrary entry once full, as the links of a run are
+	// rarely shared again much later
+	if len(resolvedLinks) >= linkResolveCacheSize {
+		for key := range resolvedLinks {
+			delete(resolvedLinks, key)
+			break
+		}
+	}
+	resolvedLinks[raw] = resolved
+
+	return resolved, err
+}
+
+// canonicalURL normalizes a url so that links to the same page compare equal
+// regardless of where they were shared from. Short links are resolved,
+// mobile hosts are mapped to the host they mirror, and the scheme, www
+// prefix, default ports, fragments, trailing slashes and tracking parameters
+// are dropped. An empty string is returned for anything that is not an
+// http url.
+func canonicalURL(raw string) string {
+	u, err := parseLink(raw)
+	if err != nil {
+		return ""
+	}
+
+	if slices.Contains(linkShorteners, linkHost(u)) {
+		resolved, err := resolveShortLink(u.String())
+		if err != nil {
+			log.WithError(err).WithField("url", raw).Warn("error resolving short link")
+		} else if resolvedURL, err := parseLink(resolved); err == nil {
+			u = resolvedURL
+		}
+	}
+
+	host := linkHost(u)
+	if alias, ok := linkHostAliases[host]; ok {
+		host = alias
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	query := u.Query()
+	if host == "youtu.be" {
+		query.Set("v", strings.TrimPrefix(path, "/"))
+		host = "youtube.com"
+		path = "/watch"
+	}
+
+	for key := range query {
+		name := strings.ToLower(key)
+		if strings.HasPrefix(name, "utm_") || slices.Contains(linkTrackingParams, name) || slices.Contains(linkHostTrackingParams[host], name) {
+			query.Del(key)
+		}
+	}
+
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host = host + ":" + port
+	}
+
+	canonical := url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+
+	return canonical.String()
+}
+
+// canonicalLinks canonicalizes the links, dropping duplicates and anything
+// that is not an http url
+func canonicalLinks(links []string) []string {
+	var canonical []string
+	for _, link := range links {
+		if c := canonicalURL(link); c != "" && !slices.Contains(canonical, c) {
+			canonical = append(canonical, c)
+		}
+	}
+
+	return canonical
+}
+
+// threadOpen reports whether later sightings of the link should still be
+// posted to its thread
+func (l Link) threadOpen(window time.Duration) bool {
+	return l.ThreadTS != "" && time.Since(l.ThreadedAt) < window
+}
+
+// threadLinks records the links the mention shares on its thread. When the
+// thread has not been started and a link was notified about within the
+// thread window, the notification is posted as a reply to that thread, and
+// otherwise it starts a new one.
+func threadLinks(thread *SlackThread, item FilterItem, config *Config, db *gorm.DB) error {
+	if config.LinkThreadWindow <= 0 {
+		return nil
+	}
+
+	thread.links = canonicalLinks(item.Links)
+	if len(thread.links) == 0 || thread.TS != "" {
+		return nil
+	}
+
+	var link Link
+	since := time.Now().Add(-config.LinkThreadWindow)
+	dbResult := db.Where("url IN ? AND slack_channel_id = ? AND thread_ts <> '' AND threaded_at > ?", thread.links, config.SlackChannelID, since).Order("threaded_at").First(&link)
+	if dbResult.Error != nil && !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error fetching link: %w", dbResult.Error)
+	}
+
+	if dbResult.Error == nil {
+		log.WithFields(log.Fields{
+			"mention_id":   thread.mentionID,
+			"source":       item.Source,
+			"url":          link.URL,
+			"first_source": link.Source,
+		}).Info("Link seen before, replying in thread")
+		thread.ChannelID = link.ThreadChannelID
+		thread.TS = link.ThreadTS
+	}
+
+	return nil
+}
+
+// recordLinks records the links shared by a mention once its notification
+// has been sent, attaching any that are not already part of an open thread
+// to the thread the notification was posted to
+func recordLinks(config *Config, db *gorm.DB) error {
+	thread := config.SlackThread
+	for _, u := range thread.links {
+		var link Link
+		dbResult := db.First(&link, "url = ? AND slack_channel_id = ?", u, config.SlackChannelID)
+		if dbResult.Error != nil && !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("error fetching link: %w", dbResult.Error)
+		}
+
+		if errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			link = Link{
+				URL:            u,
+				SlackChannelID: config.SlackChannelID,
+				Source:         thread.source,
+			}
+		}
+
+		if thread.TS != "" && !link.threadOpen(config.LinkThreadWindow) {
+			link.ThreadChannelID = thread.ChannelID
+			link.ThreadTS = thread.TS
+			link.ThreadedAt = time.Now()
+		}
+
+		if dbResult := db.Save(&link); dbResult.Error != nil {
+			return fmt.Errorf("error saving link: %w", dbResult.Error)
+		}
+
+		sighting := LinkSighting{
+			LinkID:    link.ID,
+			MentionID: thread.mentionID,
+			Source:    thread.source,
+		}
+		if dbResult := db.Create(&sighting); dbResult.Error != nil {
+			return fmt.Errorf("error inserting link sighting into database: %w", dbResult.Error)
+		}
+	}
+
+	return nil
+}
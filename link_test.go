@@ -0,0 +1,235 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	resolvedLinks["https://t.co/abc123"] = "https://www.example.com/post/?utm_source=twitter"
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "already canonical",
+			raw:  "https://example.com/post",
+			want: "https://example.com/post",
+		},
+		{
+			name: "scheme, www, fragment and trailing slash",
+			raw:  "http://www.Example.com/post/#comments",
+			want: "https://example.com/post",
+		},
+		{
+			name: "default port",
+			raw:  "https://example.com:443/post",
+			want: "https://example.com/post",
+		},
+		{
+			name: "custom port",
+			raw:  "http://example.com:8080/post",
+			want: "https://example.com:8080/post",
+		},
+		{
+			name: "global tracking parameters",
+			raw:  "https://example.com/post?utm_source=hn&UTM_Medium=x&fbclid=1&gclid=2&id=5",
+			want: "https://example.com/post?id=5",
+		},
+		{
+			name: "query is sorted",
+			raw:  "https://example.com/search?q=dokku&page=2",
+			want: "https://example.com/search?page=2&q=dokku",
+		},
+		{
+			name: "ref is kept where it selects a page",
+			raw:  "https://github.com/dokku/dokku/blob/master/README.md?ref=v0.30.0",
+			want: "https://github.com/dokku/dokku/blob/master/README.md?ref=v0.30.0",
+		},
+		{
+			name: "ref is removed on product hunt",
+			raw:  "https://www.producthunt.com/posts/dokku?ref=newsletter",
+			want: "https://producthunt.com/posts/dokku",
+		},
+		{
+			name: "source is removed on medium",
+			raw:  "https://medium.com/@someone/deploying-with-dokku-123?source=rss",
+			want: "https://medium.com/@someone/deploying-with-dokku-123",
+		},
+		{
+			name: "si is kept outside youtube and spotify",
+			raw:  "https://example.com/post?si=1",
+			want: "https://example.com/post?si=1",
+		},
+		{
+			name: "youtube share parameters",
+			raw:  "https://m.youtube.com/watch?v=abc&si=xyz&feature=shared",
+			want: "https://youtube.com/watch?v=abc",
+		},
+		{
+			name: "youtube short link",
+			raw:  "https://youtu.be/abc?si=xyz",
+			want: "https://youtube.com/watch?v=abc",
+		},
+		{
+			name: "spotify share parameter",
+			raw:  "https://open.spotify.com/episode/abc?si=xyz",
+			want: "https://open.spotify.com/episode/abc",
+		},
+		{
+			name: "twitter is mapped to x",
+			raw:  "https://mobile.twitter.com/dokku/status/1?s=20&t=abc",
+			want: "https://x.com/dokku/status/1",
+		},
+		{
+			name: "reddit mirrors",
+			raw:  "https://old.reddit.com/r/selfhosted/comments/abc/dokku/?share_id=1",
+			want: "https://reddit.com/r/selfhosted/comments/abc/dokku",
+		},
+		{
+			name: "short links are resolved",
+			raw:  "https://t.co/abc123",
+			want: "https://example.com/post",
+		},
+		{
+			name: "not an http url",
+			raw:  "mailto:someone@example.com",
+			want: "",
+		},
+		{
+			name: "relative url",
+			raw:  "/post",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalURL(tt.raw); got != tt.want {
+				t.Errorf("canonicalURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []string
+		want  []string
+	}{
+		{
+			name:  "empty",
+			links: nil,
+			want:  nil,
+		},
+		{
+			name:  "duplicates are dropped",
+			links: []string{"https://example.com/post", "http://www.example.com/post/", "https://example.com/post?utm_source=x"},
+			want:  []string{"https://example.com/post"},
+		},
+		{
+			name:  "invalid links are dropped",
+			links: []string{"", "not a url", "https://example.com/a", "https://example.com/b"},
+			want:  []string{"https://example.com/a", "https://example.com/b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalLinks(tt.links); !slices.Equal(got, tt.want) {
+				t.Errorf("canonicalLinks(%q) = %q, want %q", tt.links, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "no links",
+			text: "trying out dokku",
+			want: nil,
+		},
+		{
+			name: "trailing punctuation",
+			text: "read https://example.com/post, and (https://example.com/other).",
+			want: []string{"https://example.com/post", "https://example.com/other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := textLinks(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("textLinks(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveShortLink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/long" {
+			return
+		}
+		http.Redirect(w, r, "/long", http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	cacheSize := linkResolveCacheSize
+	linkResolveCacheSize = 2
+	defer func() { linkResolveCacheSize = cacheSize }()
+	clear(resolvedLinks)
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "redirect is followed",
+			raw:  server.URL + "/a",
+			want: server.URL + "/long",
+		},
+		{
+			name: "cached link",
+			raw:  server.URL + "/a",
+			want: server.URL + "/long",
+		},
+		{
+			name:    "failed links resolve to themselves",
+			raw:     "http://127.0.0.1:0/b",
+			want:    "http://127.0.0.1:0/b",
+			wantErr: true,
+		},
+		{
+			name: "cache is capped",
+			raw:  server.URL + "/c",
+			want: server.URL + "/long",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveShortLink(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveShortLink(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveShortLink(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+			if len(resolvedLinks) > linkResolveCacheSize {
+				t.Errorf("resolvedLinks has %d entries, want at most %d", len(resolvedLinks), linkResolveCacheSize)
+			}
+		})
+	}
+}
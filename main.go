@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	log "github.com/sirupsen/logrus"
@@ -12,15 +14,59 @@ import (
 )
 
 type Config struct {
-	DatabaseFile       string `required:"false" split_words:"true"`
-	LogFormat          string `required:"false" split_words:"true"`
-	NotifySlack        bool   `required:"false" split_words:"true"`
-	RapidApiKey        string `required:"false" split_words:"true"`
-	Site               string `required:"false" split_words:"true"`
-	SlackChannelID     string `required:"true" split_words:"true"`
-	SlackToken         string `required:"true" split_words:"true"`
-	Tag                string `required:"true" split_words:"true"`
-	TwitterBearerToken string `required:"false" split_words:"true"`
+	BlueskyAppPassword     string            `required:"false" split_words:"true"`
+	BlueskyIdentifier      string            `required:"false" split_words:"true"`
+	Classifier             *NaiveBayes       `ignored:"true"`
+	ClassifierChannelID    string            `required:"false" split_words:"true"`
+	ClassifierThreshold    float64           `default:"0.5" split_words:"true"`
+	DatabaseFile           string            `required:"false" split_words:"true"`
+	DiscourseForums        []string          `required:"false" split_words:"true"`
+	DuplicateAction        string            `default:"group" split_words:"true"`
+	DuplicateThreshold     float64           `default:"0.8" split_words:"true"`
+	DuplicateWindow        time.Duration     `default:"72h" split_words:"true"`
+	FeedKeywords           []string          `required:"false" split_words:"true"`
+	FeedUrls               []string          `required:"false" split_words:"true"`
+	FilterRules            string            `required:"false" split_words:"true"`
+	FilterRulesFile        string            `required:"false" split_words:"true"`
+	Filters                *FilterEngine     `ignored:"true"`
+	GiteaInstances         []string          `required:"false" split_words:"true"`
+	GithubCodeQueries      []string          `required:"false" split_words:"true"`
+	GithubIgnoreOrgs       []string          `required:"false" split_words:"true"`
+	GithubToken            string            `required:"false" split_words:"true"`
+	LemmyCommunities       []string          `required:"false" split_words:"true"`
+	LemmyInstances         []string          `default:"lemmy.world" split_words:"true"`
+	LinkThreadWindow       time.Duration     `default:"168h" split_words:"true"`
+	LogFormat              string            `required:"false" split_words:"true"`
+	MastodonAccessTokens   map[string]string `required:"false" split_words:"true"`
+	MastodonInstances      []string          `default:"mastodon.social" split_words:"true"`
+	MatchCaseSensitive     bool              `required:"false" split_words:"true"`
+	MatchExpression        string            `required:"false" split_words:"true"`
+	MatchMode              string            `default:"word" split_words:"true"`
+	Matcher                *Matcher          `ignored:"true"`
+	MediumBackend          string            `required:"false" split_words:"true"`
+	NostrRelays            []string          `default:"wss://relay.nostr.band,wss://nos.lol" split_words:"true"`
+	NotifySlack            bool              `required:"false" split_words:"true"`
+	ProducthuntCompetitors []string          `required:"false" split_words:"true"`
+	ProducthuntToken       string            `required:"false" split_words:"true"`
+	RapidApiKey            string            `required:"false" split_words:"true"`
+	RedditClientID         string            `required:"false" split_words:"true"`
+	RedditClientSecret     string            `required:"false" split_words:"true"`
+	RedditPassword         string            `required:"false" split_words:"true"`
+	RedditSubreddits       []string          `required:"false" split_words:"true"`
+	RedditUserAgent        string            `default:"social-notifications/1.0 (+https://github.com/dokku/social-notifications)" split_words:"true"`
+	RedditUsername         string            `required:"false" split_words:"true"`
+	RegistryNotifyVersions bool              `required:"false" split_words:"true"`
+	Site                   string            `required:"false" split_words:"true"`
+	SlackChannelID         string            `required:"true" split_words:"true"`
+	SlackThread            *SlackThread      `ignored:"true"`
+	SlackToken             string            `required:"true" split_words:"true"`
+	StackexchangeKey       string            `required:"false" split_words:"true"`
+	StackexchangeQueries   map[string]string `required:"false" split_words:"true"`
+	StackexchangeSites     []string          `required:"false" split_words:"true"`
+	StackexchangeTags      map[string]string `required:"false" split_words:"true"`
+	Tag                    string            `required:"true" split_words:"true"`
+	TwitterBearerToken     string            `required:"false" split_words:"true"`
+	YoutubeApiKey          string            `required:"false" split_words:"true"`
 }
 
 func LoadConfig() *Config {
@@ -30,6 +76,30 @@ func LoadConfig() *Config {
 		panic(err)
 	}
 
+	if config.DuplicateAction != "group" && config.DuplicateAction != "suppress" {
+		panic(fmt.Errorf("invalid duplicate action %q, must be one of group or suppress", config.DuplicateAction))
+	}
+
+	expression := config.MatchExpression
+	if expression == "" {
+		expression = config.Tag
+	}
+
+	config.Matcher, err = newMatcher(config.MatchMode, expression, config.MatchCaseSensitive)
+	if err != nil {
+		panic(err)
+	}
+
+	rules, err := loadFilterRules(&config)
+	if err != nil {
+		panic(err)
+	}
+
+	config.Filters, err = newFilterEngine(rules)
+	if err != nil {
+		panic(err)
+	}
+
 	return &config
 }
 func CreateDB(databaseFile string) (*gorm.DB, error) {
@@ -47,11 +117,33 @@ func CreateDB(databaseFile string) (*gorm.DB, error) {
 	return db, nil
 }
 
+// instanceURL normalizes an instance name into a base url
+func instanceURL(instance string) string {
+	instance = strings.TrimSuffix(instance, "/")
+	if strings.HasPrefix(instance, "http://") || strings.HasPrefix(instance, "https://") {
+		return instance
+	}
+
+	return "https://" + instance
+}
+
+// instanceHost returns the bare hostname for an instance, used as the key
+// for per-instance config and stored cursors
+func instanceHost(instance string) string {
+	instance = strings.TrimSuffix(instance, "/")
+	instance = strings.TrimPrefix(instance, "https://")
+	return strings.TrimPrefix(instance, "http://")
+}
+
 type processor func(*Config, *gorm.DB) error
 
 func main() {
 	services := flag.String("services", "", "comma-separated list of services to process")
 	notifySlack := flag.Bool("notify-slack", true, "whether to notify slack or not")
+	listMentionsFlag := flag.Bool("list-mentions", false, "list recent mentions that have not been marked as relevant or irrelevant")
+	markRelevant := flag.String("mark-relevant", "", "comma-separated list of mention ids to mark as relevant")
+	markIrrelevant := flag.String("mark-irrelevant", "", "comma-separated list of mention ids to mark as irrelevant")
+	trainClassifierFlag := flag.Bool("train-classifier", false, "retrain the relevance classifier, showing precision and recall on held-out mentions")
 	flag.Parse()
 
 	config := LoadConfig()
@@ -72,16 +164,69 @@ func main() {
 		log.WithError(err).Fatal("error creating db")
 	}
 
+	config.Classifier, err = loadClassifier(db)
+	if err != nil {
+		log.WithError(err).Fatal("error loading classifier")
+	}
+
+	if err := migrateLinks(db); err != nil {
+		log.WithError(err).Fatal("error migrating links")
+	}
+
+	if err := migrateDuplicates(db); err != nil {
+		log.WithError(err).Fatal("error migrating duplicates")
+	}
+
+	// classifier commands run on their own, without processing services
+	if *listMentionsFlag || *markRelevant != "" || *markIrrelevant != "" || *trainClassifierFlag {
+		if err := markMentions(db, *markRelevant, true); err != nil {
+			log.WithError(err).Fatal("error marking mentions")
+		}
+		if err := markMentions(db, *markIrrelevant, false); err != nil {
+			log.WithError(err).Fatal("error marking mentions")
+		}
+		if *trainClassifierFlag {
+			if err := trainClassifier(config, db); err != nil {
+				log.WithError(err).Fatal("error training classifier")
+			}
+		}
+		if *listMentionsFlag {
+			if err := listMentions(db); err != nil {
+				log.WithError(err).Fatal("error listing mentions")
+			}
+		}
+		return
+	}
+
 	processorMap := map[string]processor{
+		"bluesky":            processBluesky,
+		"codeberg":           processCodebergRepositories,
+		"crates":             processCratesPackages,
 		"devto":              processDevtoArticles,
+		"discourse":          processDiscourse,
+		"dockerhub":          processDockerhubPackages,
+		"feed":               processFeeds,
+		"gitea":              processGiteaRepositories,
 		"github":             processGithubRepositories,
+		"github_code":        processGithubCode,
+		"github_issue":       processGithubIssues,
+		"gitlab":             processGitlabRepositories,
 		"hackernews_comment": processHackernewsComments,
+		"hackernews_hiring":  processHackernewsHiring,
 		"hackernews_story":   processHackernewsStories,
+		"hashnode":           processHashnodeArticles,
+		"lemmy":              processLemmy,
 		"mastodon":           processMastodon,
 		"medium":             processMediumArticles,
+		"nostr":              processNostr,
+		"npm":                processNpmPackages,
+		"producthunt":        processProducthunt,
+		"pypi":               processPypiPackages,
 		"reddit":             processRedditPosts,
 		"stackoverflow":      processStackoverflow,
+		"substack":           processSubstackArticles,
 		"twitter":            processTwitter,
+		"youtube":            processYoutube,
 	}
 
 	// allow disabling services
@@ -18,8 +18,23 @@ import (
 var mastodonIconURL = "https://emoji.slack-edge.com/T085AJH3L/mastodon/18ff0c46d671d904.png"
 
 type MastodonToot struct {
-	ID     int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
-	TootID string `gorm:"not null" form:"toot_id" json:"toot_id"`
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	TootID   string `gorm:"not null" form:"toot_id" json:"toot_id"`
+	TootURI  string `form:"toot_uri" json:"toot_uri"`
+	Instance string `gorm:"default:mastodon.social" form:"instance" json:"instance"`
+	Language string `form:"language" json:"language"`
+}
+
+type MastodonInstanceCursor struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Instance string `gorm:"not null" form:"instance" json:"instance"`
+	SinceID  string `gorm:"not null" form:"since_id" json:"since_id"`
+}
+
+type MastodonSearchResponse struct {
+	Accounts []interface{}        `json:"accounts"`
+	Statuses []MastodonTootResult `json:"statuses"`
+	Hashtags []interface{}        `json:"hashtags"`
 }
 
 type MastodonTootResult struct {
@@ -73,18 +88,141 @@ type MastodonTootResult struct {
 		URL  string `json:"url"`
 	} `json:"tags"`
 	Emojis []interface{} `json:"emojis"`
-	Card   interface{}   `json:"card"`
-	Poll   interface{}   `json:"poll"`
+	Card   *struct {
+		URL string `json:"url"`
+	} `json:"card"`
+	Poll interface{} `json:"poll"`
+}
+
+// FilterItem converts the toot for evaluation against the filter rules,
+// where the links include the one previewed in the card
+func (r MastodonTootResult) FilterItem() FilterItem {
+	text := strings.Join([]string{r.SpoilerText, stripFeedHTML(r.Content)}, " ")
+	item := FilterItem{
+		Source:   "mastodon",
+		Text:     text,
+		Author:   r.Account.Acct,
+		Language: itemLanguage(r.Language, text),
+		URL:      r.URL,
+		Links:    []string{r.URL},
+		Metrics: map[string]float64{
+			"replies":    float64(r.RepliesCount),
+			"reblogs":    float64(r.ReblogsCount),
+			"favourites": float64(r.FavouritesCount),
+		},
+	}
+
+	if r.Card != nil {
+		item.Links = append(item.Links, r.Card.URL)
+	}
+
+	return item
 }
 
-func getToots(config *Config) ([]MastodonTootResult, error) {
-	var response []MastodonTootResult
-	client := resty.New()
-	_, err := client.R().
-		SetResult(&response).
-		Get(fmt.Sprintf("http://mastodon.social/api/v1/timelines/tag/%s", config.Tag))
+// mastodonMaxPages caps how far back each instance is paged on a single run
+var mastodonMaxPages = 10
+
+// mastodonIDNewer reports whether toot id a is newer than toot id b. ids are
+// numeric strings that are too large for some instances to fit in an int64,
+// so they are compared by length and then lexically
+func mastodonIDNewer(a string, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
 
-	return response, err
+	return a > b
+}
+
+// getToots fetches toots mentioning the tag from an instance. Once a toot
+// has been seen on the instance, toots newer than it are paged through
+// oldest first with min_id, so that a run that hits the page cap resumes
+// where it stopped. Without a cursor, the newest toots are paged back
+// through with max_id instead.
+func getToots(instance string, sinceID string, config *Config) ([]MastodonTootResult, error) {
+	var results []MastodonTootResult
+	host := instanceHost(instance)
+	token := config.MastodonAccessTokens[host]
+
+	minID := sinceID
+	maxID := ""
+	for page := 1; page <= mastodonMaxPages; page++ {
+		log.WithFields(log.Fields{
+			"instance": host,
+			"min_id":   minID,
+			"max_id":   maxID,
+		}).Info("Fetching page")
+
+		client := resty.New()
+		request := client.R()
+		if minID != "" {
+			request.SetQueryParam("min_id", minID)
+		} else if maxID != "" {
+			request.SetQueryParam("max_id", maxID)
+		}
+
+		var toots []MastodonTootResult
+		if token != "" {
+			// full-text search is only available to authenticated users
+			var response MastodonSearchResponse
+			resp, err := request.
+				SetAuthToken(token).
+				SetQueryParams(map[string]string{
+					"q":     config.Tag,
+					"type":  "statuses",
+					"limit": "40",
+				}).
+				SetResult(&response).
+				Get(fmt.Sprintf("%s/api/v2/search", instanceURL(instance)))
+			if err != nil {
+				return results, err
+			}
+			if resp.IsError() {
+				return results, fmt.Errorf("error searching toots on %s: %s", host, resp.Status())
+			}
+			toots = response.Statuses
+		} else {
+			resp, err := request.
+				SetQueryParam("limit", "40").
+				SetResult(&toots).
+				Get(fmt.Sprintf("%s/api/v1/timelines/tag/%s", instanceURL(instance), config.Tag))
+			if err != nil {
+				return results, err
+			}
+			if resp.IsError() {
+				return results, fmt.Errorf("error fetching toots on %s: %s", host, resp.Status())
+			}
+		}
+
+		if len(toots) == 0 {
+			break
+		}
+
+		for _, toot := range toots {
+			// hashtags are not always part of the content
+			values := []string{toot.SpoilerText, stripFeedHTML(toot.Content)}
+			for _, tag := range toot.Tags {
+				values = append(values, tag.Name)
+			}
+			if !config.Matcher.Verify("mastodon", toot.URI, values...) {
+				continue
+			}
+
+			results = append(results, toot)
+		}
+
+		// the next page starts after the newest toot when paging forward,
+		// and before the oldest one when paging back
+		for _, toot := range toots {
+			if minID != "" && mastodonIDNewer(toot.ID, minID) {
+				minID = toot.ID
+			}
+			if minID == "" && (maxID == "" || mastodonIDNewer(maxID, toot.ID)) {
+				maxID = toot.ID
+			}
+		}
+	}
+
+	return results, nil
 }
 
 func sendSlackNotificationForMastodonToot(result MastodonTootResult, config *Config) error {
@@ -130,61 +268,110 @@ func sendSlackNotificationForMastodonToot(result MastodonTootResult, config *Con
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
-	}
-
-	return nil
+	return postSlackMessage(config, messageOpts...)
 }
 
 func processMastodon(config *Config, db *gorm.DB) error {
-	if err := db.AutoMigrate(&MastodonToot{}); err != nil {
+	if err := db.AutoMigrate(&MastodonToot{}, &MastodonInstanceCursor{}); err != nil {
 		return fmt.Errorf("error migrating MastodonToot: %w", err)
 	}
 
-	log.Info("Fetching toots")
-	results, err := getToots(config)
-	if err != nil {
-		return err
-	}
-
 	inserted := 0
 	notified := 0
-	log.WithField("story_count", len(results)).Info("Processing toots")
-	for _, result := range results {
-		logFields := log.Fields{
-			"toot_id": result.ID,
+	processed := 0
+	for _, instance := range config.MastodonInstances {
+		host := instanceHost(instance)
+		var cursor MastodonInstanceCursor
+		if dbResult := db.First(&cursor, "instance = ?", host); dbResult.Error != nil && !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("error fetching cursor for %s: %w", host, dbResult.Error)
 		}
 
-		var entity MastodonToot
-		if dbResult := db.First(&entity, "toot_id = ?", result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
-			continue
+		log.WithField("instance", host).Info("Fetching toots")
+		results, err := getToots(instance, cursor.SinceID, config)
+		if err != nil {
+			return err
 		}
 
-		log.WithFields(logFields).Info("Inserting new toot")
-		entity = MastodonToot{
-			TootID: result.ID,
-		}
+		processed += len(results)
+		log.WithFields(log.Fields{
+			"instance":   host,
+			"toot_count": len(results),
+		}).Info("Processing toots")
+		for _, result := range results {
+			logFields := log.Fields{
+				"instance": host,
+				"toot_id":  result.ID,
+				"toot_uri": result.URI,
+			}
+
+			item := result.FilterItem()
+			if !config.Filters.Allow(item) {
+				continue
+			}
+
+			// federated toots share a uri across instances, while the
+			// id is only unique to the instance the toot was fetched from
+			var entity MastodonToot
+			if dbResult := db.First(&entity, "toot_uri = ? OR (instance = ? AND toot_id = ?)", result.URI, host, result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+				continue
+			}
+
+			log.WithFields(logFields).Info("Inserting new toot")
+			entity = MastodonToot{
+				TootID:   result.ID,
+				TootURI:  result.URI,
+				Instance: host,
+				Language: item.Language,
+			}
 
-		if dbResult := db.Create(&entity); dbResult.Error != nil {
-			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting toot into database")
-			continue
+			if dbResult := db.Create(&entity); dbResult.Error != nil {
+				log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting toot into database")
+				continue
+			}
+
+			inserted += 1
+			notifyConfig, err := prepareMention(item, config, db)
+			if err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error recording toot")
+				continue
+			}
+			if notifyConfig == nil {
+				continue
+			}
+
+			if err := sendSlackNotificationForMastodonToot(result, notifyConfig); err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error posting toot to slack")
+				continue
+			}
+
+			if err := recordNotification(notifyConfig, db); err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error recording notification for toot")
+				continue
+			}
+
+			notified += 1
 		}
 
-		inserted += 1
-		if err := sendSlackNotificationForMastodonToot(result, config); err != nil {
-			log.WithError(err).WithFields(logFields).Fatal("error posting toot to slack")
-			continue
+		sinceID := cursor.SinceID
+		for _, result := range results {
+			if mastodonIDNewer(result.ID, sinceID) {
+				sinceID = result.ID
+			}
 		}
 
-		notified += 1
+		if sinceID != cursor.SinceID {
+			cursor.Instance = host
+			cursor.SinceID = sinceID
+			if dbResult := db.Save(&cursor); dbResult.Error != nil {
+				return fmt.Errorf("error saving cursor for %s: %w", host, dbResult.Error)
+			}
+		}
 	}
 	log.WithFields(log.Fields{
-		"processed_toot_count": len(results),
+		"processed_toot_count": processed,
 		"inserted_toot_count":  inserted,
 		"notified_toot_count":  notified,
-	}).Info("Done with mastodon.social toots")
+	}).Info("Done with mastodon toots")
 
 	return nil
 }
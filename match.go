@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Matcher verifies that results returned by a search actually mention what
+// is being searched for, as most search apis match loosely and will happily
+// return "Count Dooku" or "dokkan" for "dokku". The expression defaults to
+// the tag, and is interpreted according to the mode:
+//
+// - substring: the expression appears anywhere in the text
+// - word: the expression appears as a whole word or phrase
+// - regex: the expression is a regular expression
+// - boolean: words and "quoted phrases" combined with AND, OR, NOT and
+// parentheses, where adjacent terms are implicitly combined with AND
+type Matcher struct {
+	mode          string
+	expression    string
+	caseSensitive bool
+	expr          matchExpr
+}
+
+// matchExpr is a node in a parsed expression
+type matchExpr interface {
+	match(text string) bool
+}
+
+type matchPattern struct {
+	pattern *regexp.Regexp
+}
+
+type matchAnd struct {
+	left  matchExpr
+	right matchExpr
+}
+
+type matchOr struct {
+	left  matchExpr
+	right matchExpr
+}
+
+type matchNot struct {
+	expr matchExpr
+}
+
+func (m matchPattern) match(text string) bool {
+	return m.pattern.MatchString(text)
+}
+
+func (m matchAnd) match(text string) bool {
+	return m.left.match(text) && m.right.match(text)
+}
+
+func (m matchOr) match(text string) bool {
+	return m.left.match(text) || m.right.match(text)
+}
+
+func (m matchNot) match(text string) bool {
+	return !m.expr.match(text)
+}
+
+// wordPattern compiles a regular expression matching the word or phrase
+// when it is not surrounded by other letters or digits. Whitespace within a
+// phrase matches any run of whitespace.
+func wordPattern(word string, caseSensitive bool) (*regexp.Regexp, error) {
+	var parts []string
+	for _, part := range strings.Fields(word) {
+		parts = append(parts, regexp.QuoteMeta(part))
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty word")
+	}
+
+	pattern := `(?:^|[^\pL\pN])` + strings.Join(parts, `\s+`) + `(?:$|[^\pL\pN])`
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// newMatcher parses the expression according to the mode
+func newMatcher(mode string, expression string, caseSensitive bool) (*Matcher, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("match expression must not be empty")
+	}
+
+	m := &Matcher{
+		mode:          mode,
+		expression:    expression,
+		caseSensitive: caseSensitive,
+	}
+
+	switch mode {
+	case "substring":
+		value := regexp.QuoteMeta(expression)
+		if !caseSensitive {
+			value = "(?i)" + value
+		}
+
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		m.expr = matchPattern{pattern: pattern}
+	case "word":
+		pattern, err := wordPattern(expression, caseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		m.expr = matchPattern{pattern: pattern}
+	case "regex":
+		value := expression
+		if !caseSensitive {
+			value = "(?i)" + value
+		}
+
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match regex: %w", err)
+		}
+		m.expr = matchPattern{pattern: pattern}
+	case "boolean":
+		tokens, err := tokenizeMatchExpression(expression)
+		if err != nil {
+			return nil, err
+		}
+
+		parser := &matchParser{tokens: tokens, caseSensitive: caseSensitive}
+		expr, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if parser.pos < len(parser.tokens) {
+			return nil, fmt.Errorf("unexpected %q in match expression", parser.tokens[parser.pos].value)
+		}
+		m.expr = expr
+	default:
+		return nil, fmt.Errorf("invalid match mode %q, must be one of substring, word, regex or boolean", mode)
+	}
+
+	return m, nil
+}
+
+// Match reports whether the values match the expression. The values are
+// matched as a single text, so that boolean expressions can combine terms
+// found in different fields such as a title and body.
+func (m *Matcher) Match(values ...string) bool {
+	return m.expr.match(strings.Join(values, "\n"))
+}
+
+// Verify reports whether the values match the expression, logging the result
+// that is being dropped when they do not
+func (m *Matcher) Verify(source string, id string, values ...string) bool {
+	if m.Match(values...) {
+		return true
+	}
+
+	log.WithFields(log.Fields{
+		"source":     source,
+		"id":         id,
+		"expression": m.expression,
+		"mode":       m.mode,
+	}).Info("Result does not match, skipping")
+	return false
+}
+
+type matchToken struct {
+	value  string
+	phrase bool
+}
+
+// tokenizeMatchExpression splits a boolean expression into parentheses,
+// quoted phrases and words
+func tokenizeMatchExpression(expression string) ([]matchToken, error) {
+	var tokens []matchToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, matchToken{value: string(r)})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated phrase in match expression")
+			}
+
+			tokens = append(tokens, matchToken{value: string(runes[i+1 : end]), phrase: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+
+			tokens = append(tokens, matchToken{value: string(runes[i:end])})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+// matchParser is a recursive descent parser for boolean expressions, where
+// NOT binds tighter than AND, which binds tighter than OR
+type matchParser struct {
+	tokens        []matchToken
+	pos           int
+	caseSensitive bool
+}
+
+func (p *matchParser) peek() (matchToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return matchToken{}, false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+// isOperator reports whether the token is the given operator, which must be
+// written in uppercase so that the words themselves can still be searched for
+func (p *matchParser) isOperator(token matchToken, operator string) bool {
+	return !token.phrase && token.value == operator
+}
+
+func (p *matchParser) parseOr() (matchExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		token, ok := p.peek()
+		if !ok || !p.isOperator(token, "OR") {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = matchOr{left: left, right: right}
+	}
+}
+
+func (p *matchParser) parseAnd() (matchExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		token, ok := p.peek()
+		if !ok || p.isOperator(token, "OR") || (!token.phrase && token.value == ")") {
+			return left, nil
+		}
+		if p.isOperator(token, "AND") {
+			p.pos++
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = matchAnd{left: left, right: right}
+	}
+}
+
+func (p *matchParser) parseNot() (matchExpr, error) {
+	token, ok := p.peek()
+	if ok && p.isOperator(token, "NOT") {
+		p.pos++
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return matchNot{expr: expr}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *matchParser) parsePrimary() (matchExpr, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of match expression")
+	}
+	p.pos++
+
+	if !token.phrase {
+		switch token.value {
+		case "(":
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			closing, ok := p.peek()
+			if !ok || closing.phrase || closing.value != ")" {
+				return nil, fmt.Errorf("missing closing parenthesis in match expression")
+			}
+			p.pos++
+			return expr, nil
+		case ")", "AND", "OR":
+			return nil, fmt.Errorf("unexpected %q in match expression", token.value)
+		}
+	}
+
+	pattern, err := wordPattern(token.value, p.caseSensitive)
+	if err != nil {
+		return nil, fmt.Errorf("empty phrase in match expression")
+	}
+
+	return matchPattern{pattern: pattern}, nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNewMatcher(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		expression string
+		wantErr    bool
+	}{
+		{
+			name:       "substring",
+			mode:       "substring",
+			expression: "dokku",
+		},
+		{
+			name:       "regex",
+			mode:       "regex",
+			expression: `dokku\b`,
+		},
+		{
+			name:       "boolean",
+			mode:       "boolean",
+			expression: `dokku AND (heroku OR "git push") NOT dooku`,
+		},
+		{
+			name:       "empty expression",
+			mode:       "word",
+			expression: "  ",
+			wantErr:    true,
+		},
+		{
+			name:       "invalid mode",
+			mode:       "fuzzy",
+			expression: "dokku",
+			wantErr:    true,
+		},
+		{
+			name:       "invalid regex",
+			mode:       "regex",
+			expression: "dokku(",
+			wantErr:    true,
+		},
+		{
+			name:       "unterminated phrase",
+			mode:       "boolean",
+			expression: `"git push`,
+			wantErr:    true,
+		},
+		{
+			name:       "missing closing parenthesis",
+			mode:       "boolean",
+			expression: "(dokku OR heroku",
+			wantErr:    true,
+		},
+		{
+			name:       "unexpected closing parenthesis",
+			mode:       "boolean",
+			expression: "dokku)",
+			wantErr:    true,
+		},
+		{
+			name:       "dangling operator",
+			mode:       "boolean",
+			expression: "dokku OR",
+			wantErr:    true,
+		},
+		{
+			name:       "leading operator",
+			mode:       "boolean",
+			expression: "AND dokku",
+			wantErr:    true,
+		},
+		{
+			name:       "empty phrase",
+			mode:       "boolean",
+			expression: `dokku ""`,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newMatcher(tt.mode, tt.expression, false); (err != nil) != tt.wantErr {
+				t.Errorf("newMatcher(%q, %q) error = %v, want error %v", tt.mode, tt.expression, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		expression    string
+		caseSensitive bool
+		values        []string
+		want          bool
+	}{
+		{
+			name:       "substring matches inside words",
+			mode:       "substring",
+			expression: "dokku",
+			values:     []string{"dokku-letsencrypt released"},
+			want:       true,
+		},
+		{
+			name:       "substring is literal",
+			mode:       "substring",
+			expression: "dokku.io",
+			values:     []string{"dokkuxio"},
+			want:       false,
+		},
+		{
+			name:       "word ignores case",
+			mode:       "word",
+			expression: "dokku",
+			values:     []string{"Deploying with Dokku!"},
+			want:       true,
+		},
+		{
+			name:          "word is case sensitive when configured",
+			mode:          "word",
+			expression:    "dokku",
+			caseSensitive: true,
+			values:        []string{"Deploying with Dokku"},
+			want:          false,
+		},
+		{
+			name:       "word does not match inside other words",
+			mode:       "word",
+			expression: "dokku",
+			values:     []string{"dokkan battle"},
+			want:       false,
+		},
+		{
+			name:       "word does not match a prefix",
+			mode:       "word",
+			expression: "dokku",
+			values:     []string{"dokkus"},
+			want:       false,
+		},
+		{
+			name:       "phrase matches any whitespace",
+			mode:       "word",
+			expression: "git push",
+			values:     []string{"a simple git\n  push deploy"},
+			want:       true,
+		},
+		{
+			name:       "regex",
+			mode:       "regex",
+			expression: `^dokku \d+\.\d+`,
+			values:     []string{"Dokku 0.34 released"},
+			want:       true,
+		},
+		{
+			name:       "values are matched as a single text",
+			mode:       "boolean",
+			expression: "dokku heroku",
+			values:     []string{"Moving off Heroku", "trying dokku"},
+			want:       true,
+		},
+		{
+			name:       "implicit and",
+			mode:       "boolean",
+			expression: "dokku heroku",
+			values:     []string{"trying dokku"},
+			want:       false,
+		},
+		{
+			name:       "or",
+			mode:       "boolean",
+			expression: "dokku OR coolify",
+			values:     []string{"trying coolify"},
+			want:       true,
+		},
+		{
+			name:       "not",
+			mode:       "boolean",
+			expression: "dokku NOT dooku",
+			values:     []string{"dokku or count dooku"},
+			want:       false,
+		},
+		{
+			name:       "not binds tighter than and",
+			mode:       "boolean",
+			expression: "NOT dooku dokku",
+			values:     []string{"trying dokku"},
+			want:       true,
+		},
+		{
+			name:       "and binds tighter than or",
+			mode:       "boolean",
+			expression: "dooku AND count OR dokku",
+			values:     []string{"trying dokku"},
+			want:       true,
+		},
+		{
+			name:       "parentheses",
+			mode:       "boolean",
+			expression: "dooku AND (count OR dokku)",
+			values:     []string{"trying dokku"},
+			want:       false,
+		},
+		{
+			name:       "phrase",
+			mode:       "boolean",
+			expression: `dokku "git push"`,
+			values:     []string{"dokku deploys on git push"},
+			want:       true,
+		},
+		{
+			name:       "phrase words must be adjacent",
+			mode:       "boolean",
+			expression: `dokku "git push"`,
+			values:     []string{"dokku deploys on git, then push"},
+			want:       false,
+		},
+		{
+			name:       "lowercase operators are words",
+			mode:       "boolean",
+			expression: "dokku or coolify",
+			values:     []string{"trying coolify"},
+			want:       false,
+		},
+		{
+			name:       "quoted operators are words",
+			mode:       "boolean",
+			expression: `dokku "OR" coolify`,
+			values:     []string{"dokku or coolify"},
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := newMatcher(tt.mode, tt.expression, tt.caseSensitive)
+			if err != nil {
+				t.Fatalf("newMatcher(%q, %q) error = %v", tt.mode, tt.expression, err)
+			}
+
+			if got := matcher.Match(tt.values...); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -19,6 +22,30 @@ type MediumArticle struct {
 	ID        int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
 	ArticleID string `gorm:"not null" form:"article_id" json:"article_id"`
 	Title     string `gorm:"not null" form:"title" json:"title"`
+	Language  string `form:"language" json:"language"`
+}
+
+// MediumArticleResult is an article from either the rapidapi or rss backend
+type MediumArticleResult struct {
+	ID          string
+	Title       string
+	URL         string
+	AuthorName  string
+	AuthorURL   string
+	Language    string
+	PublishedAt time.Time
+}
+
+// FilterItem converts the article for evaluation against the filter rules
+func (r MediumArticleResult) FilterItem() FilterItem {
+	return FilterItem{
+		Source:   "medium",
+		Text:     r.Title,
+		Author:   r.AuthorName,
+		Language: itemLanguage(r.Language, r.Title),
+		URL:      r.URL,
+		Links:    []string{r.URL},
+	}
 }
 
 type MediumTopFeedsResponse struct {
@@ -63,7 +90,7 @@ type MediumResult struct {
 	Subtitle       string   `json:"subtitle"`
 }
 
-func getMediumArticles(config *Config) ([]string, error) {
+func getMediumTopfeeds(config *Config) ([]string, error) {
 	var results []string
 	log.Info("Fetching page")
 	var response MediumTopFeedsResponse
@@ -118,36 +145,119 @@ func getMediumAuthor(authorID string, config *Config) (MediumAuthorResult, error
 	return response, nil
 }
 
-func sendSlackNotificationForMediumArticle(result MediumResult, config *Config) error {
-	if !config.NotifySlack {
-		return nil
+// getMediumRapidapiArticles fetches new articles via rapidapi, skipping the
+// article and author lookups for articles that have already been recorded
+func getMediumRapidapiArticles(config *Config, db *gorm.DB) ([]MediumArticleResult, error) {
+	var results []MediumArticleResult
+	articleIDs, err := getMediumTopfeeds(config)
+	if err != nil {
+		return results, err
 	}
 
-	logFields := log.Fields{
-		"article_id": result.ID,
-		"title":      result.Title,
+	for _, articleID := range articleIDs {
+		var entity MediumArticle
+		if dbResult := db.First(&entity, "article_id = ?", articleID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		article, err := getMediumArticle(articleID, config)
+		if err != nil {
+			return results, err
+		}
+
+		author, err := getMediumAuthor(article.Author, config)
+		if err != nil {
+			return results, err
+		}
+
+		values := append([]string{article.Title, article.Subtitle}, article.Tags...)
+		if !config.Matcher.Verify("medium", article.ID, values...) {
+			continue
+		}
+
+		t, err := time.Parse("2006-01-02 15:04:05", article.PublishedAt)
+		if err != nil {
+			return results, err
+		}
+
+		results = append(results, MediumArticleResult{
+			ID:          article.ID,
+			Title:       article.Title,
+			URL:         article.URL,
+			AuthorName:  author.Fullname,
+			AuthorURL:   fmt.Sprintf("https://medium.com/@%s", author.Username),
+			Language:    article.Lang,
+			PublishedAt: t,
+		})
 	}
 
-	author, err := getMediumAuthor(result.Author, config)
+	return results, nil
+}
+
+// getMediumFeedArticles fetches new articles from the public tag feed. The
+// guid of each entry links to the article id used by rapidapi, so records
+// are shared between both backends. The feed state is returned to be saved
+// once the articles have been processed.
+func getMediumFeedArticles(config *Config, db *gorm.DB) ([]MediumArticleResult, *FeedState, error) {
+	var results []MediumArticleResult
+	feedURL := fmt.Sprintf("https://medium.com/feed/tag/%s", url.PathEscape(strings.ToLower(config.Tag)))
+	items, state, err := getFeedItems(feedURL, db)
 	if err != nil {
-		return err
+		return results, nil, err
 	}
 
-	t, err := time.Parse("2006-01-02 15:04:05", result.PublishedAt)
-	if err != nil {
-		return err
+	for _, item := range items {
+		values := append([]string{item.Title, stripFeedHTML(item.Summary), stripFeedHTML(item.Content)}, item.Categories...)
+		if !config.Matcher.Verify("medium", item.ID, values...) {
+			continue
+		}
+
+		articleURL := item.Link
+		authorURL := ""
+		if u, err := url.Parse(item.Link); err == nil {
+			// drop the rss tracking parameters
+			u.RawQuery = ""
+			articleURL = u.String()
+
+			if username, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/"); strings.HasPrefix(username, "@") {
+				authorURL = fmt.Sprintf("https://medium.com/%s", username)
+			}
+		}
+
+		results = append(results, MediumArticleResult{
+			ID:          path.Base(item.ID),
+			Title:       item.Title,
+			URL:         articleURL,
+			AuthorName:  item.Author,
+			AuthorURL:   authorURL,
+			Language:    item.Language,
+			PublishedAt: item.Published,
+		})
+	}
+
+	return results, state, nil
+}
+
+func sendSlackNotificationForMediumArticle(result MediumArticleResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"article_id": result.ID,
+		"title":      result.Title,
 	}
 
 	attachment := slack.Attachment{
 		Color:      "#36a64f",
 		Fallback:   "New article on Medium!",
-		AuthorName: author.Fullname,
-		AuthorLink: fmt.Sprintf("https://medium.com/@%s", author.Username),
+		AuthorName: result.AuthorName,
+		AuthorLink: result.AuthorURL,
 		Title:      result.Title,
 		TitleLink:  result.URL,
 		Footer:     "Medium Article Notification",
 		FooterIcon: mediumIconURL,
-		Ts:         json.Number(strconv.FormatInt(int64(t.Unix()), 10)),
+		Ts:         json.Number(strconv.FormatInt(int64(result.PublishedAt.Unix()), 10)),
 	}
 
 	log.WithFields(logFields).Info("Notifying slack")
@@ -160,26 +270,40 @@ func sendSlackNotificationForMediumArticle(result MediumResult, config *Config)
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
-	}
-
-	return nil
+	return postSlackMessage(config, messageOpts...)
 }
 
 func processMediumArticles(config *Config, db *gorm.DB) error {
-	if err := db.AutoMigrate(&MediumArticle{}); err != nil {
+	if err := db.AutoMigrate(&MediumArticle{}, &FeedState{}); err != nil {
 		return fmt.Errorf("error migrating MediumArticle: %w", err)
 	}
 
-	if config.RapidApiKey == "" {
-		log.Warn("No RAPID_API_KEY specified, skipping medium")
-		return nil
+	// default to the free rss feed unless a rapidapi key is available
+	backend := config.MediumBackend
+	if backend == "" {
+		backend = "rss"
+		if config.RapidApiKey != "" {
+			backend = "rapidapi"
+		}
 	}
 
-	log.Info("Fetching articles")
-	results, err := getMediumArticles(config)
+	var results []MediumArticleResult
+	var state *FeedState
+	var err error
+	log.WithField("backend", backend).Info("Fetching articles")
+	switch backend {
+	case "rapidapi":
+		if config.RapidApiKey == "" {
+			log.Warn("No RAPID_API_KEY specified, skipping medium")
+			return nil
+		}
+
+		results, err = getMediumRapidapiArticles(config, db)
+	case "rss":
+		results, state, err = getMediumFeedArticles(config, db)
+	default:
+		return fmt.Errorf("invalid MEDIUM_BACKEND %q, must be one of rapidapi or rss", backend)
+	}
 	if err != nil {
 		return err
 	}
@@ -187,25 +311,27 @@ func processMediumArticles(config *Config, db *gorm.DB) error {
 	inserted := 0
 	notified := 0
 	log.WithField("article_count", len(results)).Info("Processing articles")
-	for _, articleID := range results {
+	for _, result := range results {
 		logFields := log.Fields{
-			"article_id": articleID,
+			"article_id": result.ID,
+			"title":      result.Title,
 		}
 
-		var entity MediumArticle
-		if dbResult := db.First(&entity, "article_id = ?", articleID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
 			continue
 		}
 
-		result, err := getMediumArticle(articleID, config)
-		if err != nil {
-			return err
+		var entity MediumArticle
+		if dbResult := db.First(&entity, "article_id = ?", result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
 		}
 
 		log.WithFields(logFields).Info("Inserting new article")
 		entity = MediumArticle{
 			ArticleID: result.ID,
 			Title:     result.Title,
+			Language:  item.Language,
 		}
 
 		if dbResult := db.Create(&entity); dbResult.Error != nil {
@@ -214,13 +340,33 @@ func processMediumArticles(config *Config, db *gorm.DB) error {
 		}
 
 		inserted += 1
-		if err := sendSlackNotificationForMediumArticle(result, config); err != nil {
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording article")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForMediumArticle(result, notifyConfig); err != nil {
 			log.WithError(err).WithFields(logFields).Fatal("error posting article to slack")
 			continue
 		}
 
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for article")
+			continue
+		}
+
 		notified += 1
 	}
+
+	// only remember the feed as seen once its articles have been recorded
+	if err := saveFeedState(state, db); err != nil {
+		return err
+	}
+
 	log.WithFields(log.Fields{
 		"processed_article_count": len(results),
 		"inserted_article_count":  inserted,
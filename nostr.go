@@ -0,0 +1,731 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/go-resty/resty/v2"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var nostrIconURL = "https://nostr.com/favicon.ico"
+
+// nostrTimeout caps how long a relay is given to send its stored events
+var nostrTimeout = 30 * time.Second
+
+// nostrLimit caps how many events are requested from a relay at once. When a
+// relay returns a full page, older events are requested until it runs out.
+var nostrLimit = 500
+
+// nostrInitialLookback is how far back the first request to a relay looks
+// when there is no stored cursor
+var nostrInitialLookback = 24 * time.Hour
+
+var bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+type NostrNote struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	EventID  string `gorm:"not null" form:"event_id" json:"event_id"`
+	Relay    string `gorm:"not null" form:"relay" json:"relay"`
+	Language string `form:"language" json:"language"`
+}
+
+type NostrRelayCursor struct {
+	ID    int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Relay string `gorm:"not null" form:"relay" json:"relay"`
+	Since int64  `gorm:"not null" form:"since" json:"since"`
+}
+
+type NostrEvent struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// Serialize returns the event in the form described in NIP-01, whose sha256
+// hash is the id of the event
+func (e NostrEvent) Serialize() []byte {
+	var sb strings.Builder
+	sb.WriteString("[0,")
+	writeNostrString(&sb, e.PubKey)
+	sb.WriteString(",")
+	sb.WriteString(strconv.FormatInt(e.CreatedAt, 10))
+	sb.WriteString(",")
+	sb.WriteString(strconv.Itoa(e.Kind))
+	sb.WriteString(",[")
+	for i, tag := range e.Tags {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+
+		sb.WriteString("[")
+		for j, value := range tag {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			writeNostrString(&sb, value)
+		}
+		sb.WriteString("]")
+	}
+	sb.WriteString("],")
+	writeNostrString(&sb, e.Content)
+	sb.WriteString("]")
+
+	return []byte(sb.String())
+}
+
+// writeNostrString writes a json string, escaping only the characters NIP-01
+// lists. encoding/json can not be used, as it also escapes html characters
+// and line separators, which changes the hash.
+func writeNostrString(sb *strings.Builder, value string) {
+	sb.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// Verify checks that the id of the event is the hash of its contents and
+// that the id was signed by the author, as relays may pass along events
+// that were forged or tampered with
+func (e NostrEvent) Verify() error {
+	hash := sha256.Sum256(e.Serialize())
+	if hex.EncodeToString(hash[:]) != e.ID {
+		return errors.New("event id does not match its contents")
+	}
+
+	pubkey, err := hex.DecodeString(e.PubKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	key, err := schnorr.ParsePubKey(pubkey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	sig, err := hex.DecodeString(e.Sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	signature, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	if !signature.Verify(hash[:], key) {
+		return errors.New("signature does not match the event id")
+	}
+
+	return nil
+}
+
+// verifiedNostrEvents drops events that are not of the requested kind or
+// fail verification, as relays do not have to honor the filter
+func verifiedNostrEvents(relay string, kind int, events []NostrEvent) []NostrEvent {
+	var verified []NostrEvent
+	for _, event := range events {
+		logFields := log.Fields{
+			"relay":    relay,
+			"event_id": event.ID,
+		}
+
+		if event.Kind != kind {
+			log.WithFields(logFields).WithField("kind", event.Kind).Warn("unexpected event kind, skipping event")
+			continue
+		}
+
+		if err := event.Verify(); err != nil {
+			log.WithError(err).WithFields(logFields).Warn("invalid event, skipping event")
+			continue
+		}
+
+		verified = append(verified, event)
+	}
+
+	return verified
+}
+
+// NostrFilter is a subscription filter as described in NIP-01, with the
+// search extension from NIP-50
+type NostrFilter struct {
+	Kinds   []int    `json:"kinds,omitempty"`
+	Authors []string `json:"authors,omitempty"`
+	Hashtag []string `json:"#t,omitempty"`
+	Search  string   `json:"search,omitempty"`
+	Since   int64    `json:"since,omitempty"`
+	Until   int64    `json:"until,omitempty"`
+	Limit   int      `json:"limit,omitempty"`
+}
+
+// NostrRelayInformation is the relay information document from NIP-11
+type NostrRelayInformation struct {
+	Name          string `json:"name"`
+	SupportedNIPs []int  `json:"supported_nips"`
+	Limitation    struct {
+		MaxLimit int `json:"max_limit"`
+	} `json:"limitation"`
+}
+
+// NostrProfile is the content of a kind 0 metadata event
+type NostrProfile struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Picture     string `json:"picture"`
+}
+
+type NostrNoteResult struct {
+	Event   NostrEvent
+	Relay   string
+	Profile NostrProfile
+}
+
+// Npub returns the bech32-encoded public key of the author
+func (r NostrNoteResult) Npub() string {
+	return bech32EncodeHex("npub", r.Event.PubKey)
+}
+
+// AuthorName returns the profile name of the author, falling back to a
+// shortened npub when the profile is unknown
+func (r NostrNoteResult) AuthorName() string {
+	if r.Profile.DisplayName != "" {
+		return r.Profile.DisplayName
+	}
+	if r.Profile.Name != "" {
+		return r.Profile.Name
+	}
+
+	npub := r.Npub()
+	if len(npub) > 16 {
+		return npub[:16] + "…"
+	}
+
+	return npub
+}
+
+// Link returns a web link to the note
+func (r NostrNoteResult) Link() string {
+	return fmt.Sprintf("https://njump.me/%s", bech32EncodeHex("note", r.Event.ID))
+}
+
+// FilterItem converts the note for evaluation against the filter rules, where
+// the author is the profile name or the npub when the profile is unknown
+func (r NostrNoteResult) FilterItem() FilterItem {
+	author := r.Profile.Name
+	if author == "" {
+		author = r.Npub()
+	}
+
+	return FilterItem{
+		Source:   "nostr",
+		Text:     r.Event.Content,
+		Author:   author,
+		Language: detectLanguage(r.Event.Content),
+		URL:      r.Link(),
+		Links:    append([]string{r.Link()}, textLinks(r.Event.Content)...),
+	}
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+// bech32EncodeHex encodes a hex string as bech32 with the given prefix, as
+// used for the npub and note identifiers in NIP-19
+func bech32EncodeHex(hrp string, value string) string {
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		return value
+	}
+
+	// regroup the 8-bit bytes into 5-bit groups
+	var converted []byte
+	acc := 0
+	bits := 0
+	for _, b := range data {
+		acc = acc<<8 | int(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			converted = append(converted, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		converted = append(converted, byte(acc<<(5-bits)&31))
+	}
+
+	var values []byte
+	for _, c := range hrp {
+		values = append(values, byte(c>>5))
+	}
+	values = append(values, 0)
+	for _, c := range hrp {
+		values = append(values, byte(c&31))
+	}
+	values = append(values, converted...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	polymod := bech32Polymod(values) ^ 1
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteString("1")
+	for _, v := range converted {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+
+	return sb.String()
+}
+
+// getNostrRelayInformation fetches the NIP-11 document of a relay, which is
+// served over http from the same url as the websocket
+func getNostrRelayInformation(relay string) (NostrRelayInformation, error) {
+	var information NostrRelayInformation
+	u, err := url.Parse(relay)
+	if err != nil {
+		return information, err
+	}
+
+	switch u.Scheme {
+	case "wss":
+		u.Scheme = "https"
+	case "ws":
+		u.Scheme = "http"
+	}
+
+	client := resty.New().SetTimeout(nostrTimeout)
+	resp, err := client.R().
+		SetHeader("Accept", "application/nostr+json").
+		Get(u.String())
+	if err != nil {
+		return information, err
+	}
+
+	if resp.IsError() {
+		return information, fmt.Errorf("error fetching relay information for %s: %s", relay, resp.Status())
+	}
+
+	if err := json.Unmarshal(resp.Body(), &information); err != nil {
+		return information, fmt.Errorf("error parsing relay information for %s: %w", relay, err)
+	}
+
+	return information, nil
+}
+
+// queryNostrRelay sends a subscription to a relay and returns the stored
+// events it sends back, closing the subscription once the relay signals
+// the end of stored events
+func queryNostrRelay(relay string, filter NostrFilter) ([]NostrEvent, error) {
+	var events []NostrEvent
+	dialer := websocket.Dialer{HandshakeTimeout: nostrTimeout}
+	conn, _, err := dialer.Dial(relay, nil)
+	if err != nil {
+		return events, fmt.Errorf("error connecting to %s: %w", relay, err)
+	}
+	defer conn.Close()
+
+	subscriptionID := fmt.Sprintf("social-notifications-%d", time.Now().UnixNano())
+	if err := conn.WriteJSON([]interface{}{"REQ", subscriptionID, filter}); err != nil {
+		return events, fmt.Errorf("error subscribing to %s: %w", relay, err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(nostrTimeout)); err != nil {
+		return events, err
+	}
+
+	for {
+		var message []json.RawMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return events, fmt.Errorf("error reading from %s: %w", relay, err)
+		}
+
+		if len(message) < 2 {
+			continue
+		}
+
+		var messageType string
+		if err := json.Unmarshal(message[0], &messageType); err != nil {
+			continue
+		}
+
+		if messageType == "NOTICE" {
+			log.WithField("relay", relay).Warn(string(message[1]))
+			continue
+		}
+
+		var messageSubscriptionID string
+		if err := json.Unmarshal(message[1], &messageSubscriptionID); err != nil || messageSubscriptionID != subscriptionID {
+			continue
+		}
+
+		switch messageType {
+		case "EVENT":
+			if len(message) < 3 {
+				continue
+			}
+
+			var event NostrEvent
+			if err := json.Unmarshal(message[2], &event); err != nil {
+				log.WithError(err).WithField("relay", relay).Warn("error parsing event")
+				continue
+			}
+
+			events = append(events, event)
+		case "EOSE":
+			if err := conn.WriteJSON([]interface{}{"CLOSE", subscriptionID}); err != nil {
+				log.WithError(err).WithField("relay", relay).Warn("error closing subscription")
+			}
+
+			return events, nil
+		case "CLOSED":
+			return events, fmt.Errorf("subscription closed by %s: %s", relay, string(message[len(message)-1]))
+		}
+	}
+}
+
+// getNostrNotes fetches notes mentioning the tag from a relay, using full
+// text search on relays that support NIP-50 and the hashtag filter otherwise.
+// Relays return the newest events first, so when a page is full the next
+// page is requested with until set to the oldest event seen, until the relay
+// returns a partial page and every event since the cursor has been fetched.
+func getNostrNotes(relay string, since int64, config *Config) ([]NostrEvent, error) {
+	filter := NostrFilter{
+		Kinds: []int{1},
+		Since: since,
+		Limit: nostrLimit,
+	}
+
+	information, err := getNostrRelayInformation(relay)
+	if err != nil {
+		log.WithError(err).WithField("relay", relay).Warn("error fetching relay information, using hashtag filter")
+	}
+
+	if slices.Contains(information.SupportedNIPs, 50) {
+		filter.Search = config.Tag
+	} else {
+		filter.Hashtag = []string{strings.ToLower(config.Tag)}
+	}
+
+	// relays silently cap the limit, which would otherwise look like the
+	// last page
+	if maxLimit := information.Limitation.MaxLimit; maxLimit > 0 && maxLimit < filter.Limit {
+		filter.Limit = maxLimit
+	}
+
+	var events []NostrEvent
+	seen := map[string]bool{}
+	for {
+		page, err := queryNostrRelay(relay, filter)
+		if err != nil {
+			return events, err
+		}
+
+		// events created in the same second as the oldest one are returned
+		// again, so stop once a page brings nothing new. Events are verified
+		// first so that a forged copy can not hide the real event.
+		added := 0
+		for _, event := range verifiedNostrEvents(relay, 1, page) {
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+
+			events = append(events, event)
+			added += 1
+			if filter.Until == 0 || event.CreatedAt < filter.Until {
+				filter.Until = event.CreatedAt
+			}
+		}
+
+		if len(page) < filter.Limit || added == 0 {
+			break
+		}
+
+		log.WithFields(log.Fields{
+			"relay": relay,
+			"until": filter.Until,
+		}).Info("Fetching older notes")
+	}
+
+	// search results are fuzzy, so make sure the tag is actually mentioned
+	var results []NostrEvent
+	for _, event := range events {
+		if filter.Search != "" && !config.Matcher.Verify("nostr", event.ID, event.Content) {
+			continue
+		}
+
+		results = append(results, event)
+	}
+
+	return results, nil
+}
+
+// getNostrProfiles fetches the profile metadata for the given public keys
+func getNostrProfiles(relay string, pubkeys []string) (map[string]NostrProfile, error) {
+	profiles := map[string]NostrProfile{}
+	if len(pubkeys) == 0 {
+		return profiles, nil
+	}
+
+	events, err := queryNostrRelay(relay, NostrFilter{
+		Kinds:   []int{0},
+		Authors: pubkeys,
+	})
+	if err != nil {
+		return profiles, err
+	}
+
+	// relays may return multiple metadata events, only the newest applies
+	createdAt := map[string]int64{}
+	for _, event := range verifiedNostrEvents(relay, 0, events) {
+		if event.CreatedAt < createdAt[event.PubKey] {
+			continue
+		}
+
+		var profile NostrProfile
+		if err := json.Unmarshal([]byte(event.Content), &profile); err != nil {
+			continue
+		}
+
+		profiles[event.PubKey] = profile
+		createdAt[event.PubKey] = event.CreatedAt
+	}
+
+	return profiles, nil
+}
+
+func sendSlackNotificationForNostrNote(result NostrNoteResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"event_id": result.Event.ID,
+		"relay":    result.Relay,
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Author",
+			Value: result.Npub(),
+			Short: false,
+		},
+		{
+			Title: "Relay",
+			Value: result.Relay,
+			Short: true,
+		},
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New note on Nostr!",
+		AuthorName: result.AuthorName(),
+		AuthorLink: fmt.Sprintf("https://njump.me/%s", result.Npub()),
+		AuthorIcon: result.Profile.Picture,
+		Text:       result.Event.Content,
+		Footer:     "Nostr Note Notification",
+		FooterIcon: nostrIconURL,
+		Ts:         json.Number(strconv.FormatInt(result.Event.CreatedAt, 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":nostr:"),
+		slack.MsgOptionText("New note on <"+result.Link()+"|Nostr>", false),
+		slack.MsgOptionUsername("Nostr Note Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processNostr(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&NostrNote{}, &NostrRelayCursor{}); err != nil {
+		return fmt.Errorf("error migrating NostrNote: %w", err)
+	}
+
+	if len(config.NostrRelays) == 0 {
+		log.Warn("No NOSTR_RELAYS specified, skipping nostr")
+		return nil
+	}
+
+	// the same note is usually published to several relays, so collect
+	// notes from every relay before deduping them by event id
+	var results []NostrNoteResult
+	var cursors []NostrRelayCursor
+	seen := map[string]bool{}
+	for _, relay := range config.NostrRelays {
+		var cursor NostrRelayCursor
+		if dbResult := db.First(&cursor, "relay = ?", relay); dbResult.Error != nil && !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("error fetching cursor for %s: %w", relay, dbResult.Error)
+		}
+
+		since := cursor.Since
+		if since == 0 {
+			since = time.Now().Add(-nostrInitialLookback).Unix()
+		}
+
+		log.WithField("relay", relay).Info("Fetching notes")
+		events, err := getNostrNotes(relay, since, config)
+		if err != nil {
+			log.WithError(err).WithField("relay", relay).Warn("error fetching notes, skipping relay")
+			continue
+		}
+
+		var pubkeys []string
+		var relayResults []NostrNoteResult
+		latest := cursor.Since
+		for _, event := range events {
+			if event.CreatedAt > latest {
+				latest = event.CreatedAt
+			}
+
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+
+			var entity NostrNote
+			if dbResult := db.First(&entity, "event_id = ?", event.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+				continue
+			}
+
+			relayResults = append(relayResults, NostrNoteResult{Event: event, Relay: relay})
+			if !slices.Contains(pubkeys, event.PubKey) {
+				pubkeys = append(pubkeys, event.PubKey)
+			}
+		}
+
+		profiles, err := getNostrProfiles(relay, pubkeys)
+		if err != nil {
+			log.WithError(err).WithField("relay", relay).Warn("error fetching profiles")
+		}
+
+		for i := range relayResults {
+			relayResults[i].Profile = profiles[relayResults[i].Event.PubKey]
+		}
+		results = append(results, relayResults...)
+
+		if latest > cursor.Since {
+			cursor.Relay = relay
+			cursor.Since = latest
+			cursors = append(cursors, cursor)
+		}
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("note_count", len(results)).Info("Processing notes")
+	for _, result := range results {
+		logFields := log.Fields{
+			"event_id": result.Event.ID,
+			"relay":    result.Relay,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new note")
+		entity := NostrNote{
+			EventID:  result.Event.ID,
+			Relay:    result.Relay,
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting note into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording note")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForNostrNote(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting note to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for note")
+			continue
+		}
+
+		notified += 1
+	}
+
+	// cursors are only advanced once the notes have been recorded
+	for _, cursor := range cursors {
+		if dbResult := db.Save(&cursor); dbResult.Error != nil {
+			return fmt.Errorf("error saving cursor for %s: %w", cursor.Relay, dbResult.Error)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"processed_note_count": len(results),
+		"inserted_note_count":  inserted,
+		"notified_note_count":  notified,
+	}).Info("Done with nostr notes")
+
+	return nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/gorilla/websocket"
+)
+
+// signTestNostrEvent fills in the author, id and signature of the event
+func signTestNostrEvent(t *testing.T, key *btcec.PrivateKey, event NostrEvent) NostrEvent {
+	t.Helper()
+	event.PubKey = hex.EncodeToString(schnorr.SerializePubKey(key.PubKey()))
+	hash := sha256.Sum256(event.Serialize())
+	event.ID = hex.EncodeToString(hash[:])
+
+	signature, err := schnorr.Sign(key, hash[:])
+	if err != nil {
+		t.Fatalf("error signing event: %v", err)
+	}
+	event.Sig = hex.EncodeToString(signature.Serialize())
+
+	return event
+}
+
+func newTestNostrKey(t *testing.T, seed byte) *btcec.PrivateKey {
+	t.Helper()
+	key, _ := btcec.PrivKeyFromBytes(append(make([]byte, 31), seed))
+	return key
+}
+
+// newTestNostrRelay starts a relay that serves the information document over
+// http and hands each subscription to the handler over a websocket
+func newTestNostrRelay(t *testing.T, information NostrRelayInformation, handler func(conn *websocket.Conn, subscriptionID string, filter NostrFilter)) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocket.IsWebSocketUpgrade(r) {
+			w.Header().Set("Content-Type", "application/nostr+json")
+			json.NewEncoder(w).Encode(information)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("error upgrading connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var request []json.RawMessage
+		if err := conn.ReadJSON(&request); err != nil {
+			t.Errorf("error reading request: %v", err)
+			return
+		}
+
+		var messageType, subscriptionID string
+		var filter NostrFilter
+		json.Unmarshal(request[0], &messageType)
+		json.Unmarshal(request[1], &subscriptionID)
+		json.Unmarshal(request[2], &filter)
+		if messageType != "REQ" {
+			t.Errorf("expected REQ, got %s", messageType)
+			return
+		}
+
+		handler(conn, subscriptionID, filter)
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestBech32EncodeHex(t *testing.T) {
+	tests := []struct {
+		name  string
+		hrp   string
+		value string
+		want  string
+	}{
+		{
+			name:  "npub",
+			hrp:   "npub",
+			value: "7e7e9c42a91bfef19fa929e5fda1b72e0ebc1a4c1141673e2794234d86addf4e",
+			want:  "npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg",
+		},
+		{
+			name:  "nsec",
+			hrp:   "nsec",
+			value: "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa",
+			want:  "nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe5",
+		},
+		{
+			name:  "invalid hex is returned as is",
+			hrp:   "npub",
+			value: "not-hex",
+			want:  "not-hex",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bech32EncodeHex(tt.hrp, tt.value); got != tt.want {
+				t.Errorf("bech32EncodeHex(%q, %q) = %q, want %q", tt.hrp, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNostrEventSerialize(t *testing.T) {
+	tests := []struct {
+		name  string
+		event NostrEvent
+		want  string
+	}{
+		{
+			name: "no tags",
+			event: NostrEvent{
+				PubKey:    "abc",
+				CreatedAt: 1700000000,
+				Kind:      1,
+				Content:   "hello dokku",
+			},
+			want: `[0,"abc",1700000000,1,[],"hello dokku"]`,
+		},
+		{
+			name: "tags",
+			event: NostrEvent{
+				PubKey:    "abc",
+				CreatedAt: 1700000000,
+				Kind:      1,
+				Tags:      [][]string{{"t", "dokku"}, {"p", "def", "wss://relay.example.com"}},
+				Content:   "hello",
+			},
+			want: `[0,"abc",1700000000,1,[["t","dokku"],["p","def","wss://relay.example.com"]],"hello"]`,
+		},
+		{
+			name: "escaped characters",
+			event: NostrEvent{
+				PubKey:  "abc",
+				Kind:    1,
+				Content: "a \"quote\"\\\n\r\t\b\f",
+			},
+			want: `[0,"abc",0,1,[],"a \"quote\"\\\n\r\t\b\f"]`,
+		},
+		{
+			name: "html and line separators are not escaped",
+			event: NostrEvent{
+				PubKey:  "abc",
+				Kind:    1,
+				Content: "<a href=\"x\">&</a>\u2028",
+			},
+			want: "[0,\"abc\",0,1,[],\"<a href=\\\"x\\\">&</a>\u2028\"]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.event.Serialize()); got != tt.want {
+				t.Errorf("Serialize() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNostrEventVerify(t *testing.T) {
+	key := newTestNostrKey(t, 1)
+	otherKey := newTestNostrKey(t, 2)
+	event := signTestNostrEvent(t, key, NostrEvent{
+		CreatedAt: 1700000000,
+		Kind:      1,
+		Tags:      [][]string{{"t", "dokku"}},
+		Content:   "deploying with <dokku> & friends",
+	})
+
+	tests := []struct {
+		name    string
+		modify  func(event NostrEvent) NostrEvent
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(event NostrEvent) NostrEvent { return event },
+		},
+		{
+			name: "tampered content",
+			modify: func(event NostrEvent) NostrEvent {
+				event.Content = "deploying with heroku"
+				return event
+			},
+			wantErr: "event id does not match its contents",
+		},
+		{
+			name: "tampered kind",
+			modify: func(event NostrEvent) NostrEvent {
+				event.Kind = 0
+				return event
+			},
+			wantErr: "event id does not match its contents",
+		},
+		{
+			name: "signed by someone else",
+			modify: func(event NostrEvent) NostrEvent {
+				forged := signTestNostrEvent(t, otherKey, event)
+				forged.PubKey = event.PubKey
+				forged.ID = event.ID
+				return forged
+			},
+			wantErr: "signature does not match the event id",
+		},
+		{
+			name: "invalid public key",
+			modify: func(event NostrEvent) NostrEvent {
+				event.PubKey = "zz"
+				hash := sha256.Sum256(event.Serialize())
+				event.ID = hex.EncodeToString(hash[:])
+				return event
+			},
+			wantErr: "invalid public key",
+		},
+		{
+			name: "invalid signature",
+			modify: func(event NostrEvent) NostrEvent {
+				event.Sig = "abcd"
+				return event
+			},
+			wantErr: "invalid signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.modify(event).Verify()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Verify() = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryNostrRelay(t *testing.T) {
+	key := newTestNostrKey(t, 1)
+	event := signTestNostrEvent(t, key, NostrEvent{CreatedAt: 1700000000, Kind: 1, Content: "dokku"})
+	otherEvent := signTestNostrEvent(t, key, NostrEvent{CreatedAt: 1700000001, Kind: 1, Content: "other subscription"})
+
+	tests := []struct {
+		name      string
+		end       []interface{}
+		wantIDs   []string
+		wantErr   string
+		wantClose bool
+	}{
+		{
+			name:      "end of stored events",
+			end:       []interface{}{"EOSE"},
+			wantIDs:   []string{event.ID},
+			wantClose: true,
+		},
+		{
+			name:    "closed by relay",
+			end:     []interface{}{"CLOSED", "error: too many subscriptions"},
+			wantErr: "too many subscriptions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			closed := make(chan bool, 1)
+			relay := newTestNostrRelay(t, NostrRelayInformation{}, func(conn *websocket.Conn, subscriptionID string, filter NostrFilter) {
+				conn.WriteJSON([]interface{}{"NOTICE", "welcome"})
+				conn.WriteJSON([]interface{}{"EVENT", "someone-else", otherEvent})
+				conn.WriteJSON([]interface{}{"EVENT", subscriptionID, event})
+
+				end := append([]interface{}{tt.end[0], subscriptionID}, tt.end[1:]...)
+				conn.WriteJSON(end)
+
+				var message []string
+				closed <- conn.ReadJSON(&message) == nil && len(message) == 2 && message[0] == "CLOSE" && message[1] == subscriptionID
+			})
+
+			events, err := queryNostrRelay(relay, NostrFilter{Kinds: []int{1}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("queryNostrRelay() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("queryNostrRelay() error = %v", err)
+			}
+
+			var ids []string
+			for _, event := range events {
+				ids = append(ids, event.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("queryNostrRelay() ids = %v, want %v", ids, tt.wantIDs)
+			}
+
+			if tt.wantClose && !<-closed {
+				t.Errorf("expected the subscription to be closed")
+			}
+		})
+	}
+}
+
+func TestGetNostrNotes(t *testing.T) {
+	key := newTestNostrKey(t, 1)
+	var stored []NostrEvent
+	for createdAt := int64(100); createdAt <= 106; createdAt++ {
+		stored = append(stored, signTestNostrEvent(t, key, NostrEvent{CreatedAt: createdAt, Kind: 1, Content: "dokku"}))
+	}
+
+	// a second note in the same second as a page boundary
+	stored = append(stored, signTestNostrEvent(t, key, NostrEvent{CreatedAt: 104, Kind: 1, Content: "dokku again"}))
+
+	forged := stored[0]
+	forged.Content = "forged"
+	forged.CreatedAt = 103
+	stored = append(stored, forged)
+
+	profile := signTestNostrEvent(t, key, NostrEvent{CreatedAt: 102, Kind: 0, Content: "{}"})
+	stored = append(stored, profile)
+
+	sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt > stored[j].CreatedAt })
+
+	tests := []struct {
+		name      string
+		since     int64
+		maxLimit  int
+		wantCount int
+		wantPages int
+	}{
+		{
+			name:      "single page",
+			since:     0,
+			maxLimit:  0,
+			wantCount: 8,
+			wantPages: 1,
+		},
+		{
+			name:      "pages past the relay limit",
+			since:     0,
+			maxLimit:  3,
+			wantCount: 8,
+			wantPages: 5,
+		},
+		{
+			name:      "stops at the cursor",
+			since:     105,
+			maxLimit:  3,
+			wantCount: 2,
+			wantPages: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			information := NostrRelayInformation{}
+			information.Limitation.MaxLimit = tt.maxLimit
+
+			pages := 0
+			relay := newTestNostrRelay(t, information, func(conn *websocket.Conn, subscriptionID string, filter NostrFilter) {
+				pages += 1
+				sent := 0
+				for _, event := range stored {
+					if event.CreatedAt < filter.Since || (filter.Until > 0 && event.CreatedAt > filter.Until) {
+						continue
+					}
+					if filter.Limit > 0 && sent == filter.Limit {
+						break
+					}
+
+					conn.WriteJSON([]interface{}{"EVENT", subscriptionID, event})
+					sent += 1
+				}
+				conn.WriteJSON([]interface{}{"EOSE", subscriptionID})
+			})
+
+			events, err := getNostrNotes(relay, tt.since, &Config{Tag: "dokku"})
+			if err != nil {
+				t.Fatalf("getNostrNotes() error = %v", err)
+			}
+
+			if len(events) != tt.wantCount {
+				t.Errorf("getNostrNotes() returned %d events, want %d", len(events), tt.wantCount)
+			}
+			if pages != tt.wantPages {
+				t.Errorf("getNostrNotes() requested %d pages, want %d", pages, tt.wantPages)
+			}
+
+			for _, event := range events {
+				if event.Kind != 1 || event.Content == "forged" {
+					t.Errorf("getNostrNotes() returned unexpected event %+v", event)
+				}
+			}
+		})
+	}
+}
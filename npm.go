@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var npmRegistry = Registry{
+	Service:   "npm",
+	Name:      "npm",
+	IconURL:   "https://www.npmjs.com/favicon.ico",
+	IconEmoji: ":npm:",
+}
+
+// npmMaxPages caps how many pages are fetched on a single run
+var npmMaxPages = 4
+
+type NpmSearchResponse struct {
+	Objects []struct {
+		Package NpmPackage `json:"package"`
+	} `json:"objects"`
+	Total int `json:"total"`
+}
+
+type NpmPackage struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Description string    `json:"description"`
+	Keywords    []string  `json:"keywords"`
+	Date        time.Time `json:"date"`
+	Links       struct {
+		NPM        string `json:"npm"`
+		Homepage   string `json:"homepage"`
+		Repository string `json:"repository"`
+	} `json:"links"`
+	Publisher struct {
+		Username string `json:"username"`
+	} `json:"publisher"`
+}
+
+func getNpmPackages(config *Config) ([]PackageResult, error) {
+	var results []PackageResult
+	size := 250
+	for page := 0; page < npmMaxPages; page++ {
+		log.WithField("page", page+1).Info("Fetching page")
+		var response NpmSearchResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"text": config.Tag,
+				"size": strconv.FormatInt(int64(size), 10),
+				"from": strconv.FormatInt(int64(page*size), 10),
+			}).
+			SetResult(&response).
+			Get("https://registry.npmjs.org/-/v1/search")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching npm packages: %s", resp.Status())
+		}
+
+		for _, object := range response.Objects {
+			pkg := object.Package
+			values := append([]string{pkg.Name, pkg.Description}, pkg.Keywords...)
+			if !config.Matcher.Verify("npm", pkg.Name, values...) {
+				continue
+			}
+
+			url := pkg.Links.NPM
+			if url == "" {
+				url = fmt.Sprintf("https://www.npmjs.com/package/%s", pkg.Name)
+			}
+
+			authorURL := ""
+			if pkg.Publisher.Username != "" {
+				authorURL = fmt.Sprintf("https://www.npmjs.com/~%s", pkg.Publisher.Username)
+			}
+
+			results = append(results, PackageResult{
+				Registry:    npmRegistry,
+				Name:        pkg.Name,
+				Version:     pkg.Version,
+				Description: pkg.Description,
+				Author:      pkg.Publisher.Username,
+				AuthorURL:   authorURL,
+				URL:         url,
+				PublishedAt: pkg.Date,
+			})
+		}
+
+		if len(response.Objects) < size || (page+1)*size >= response.Total {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func processNpmPackages(config *Config, db *gorm.DB) error {
+	log.Info("Fetching packages")
+	results, err := getNpmPackages(config)
+	if err != nil {
+		return err
+	}
+
+	return processRegistryPackages(results, config, db)
+}
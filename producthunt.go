@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var producthuntIconURL = "https://www.producthunt.com/favicon.ico"
+
+// producthuntMaxPages caps how many pages of launches are fetched on a
+// single run
+var producthuntMaxPages = 10
+
+// producthuntLookback is how far back launches are fetched, as the api has no
+// search and every recent launch has to be checked for mentions
+var producthuntLookback = 2 * 24 * time.Hour
+
+var producthuntPostsQuery = `query Posts($postedAfter: DateTime!, $after: String) {
+  posts(order: NEWEST, postedAfter: $postedAfter, first: 20, after: $after) {
+    edges {
+      node {
+        id
+        name
+        tagline
+        description
+        url
+        website
+        votesCount
+        commentsCount
+        createdAt
+        thumbnail {
+          url
+        }
+        makers {
+          name
+          username
+          url
+        }
+        topics(first: 5) {
+          edges {
+            node {
+              name
+            }
+          }
+        }
+      }
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}`
+
+type ProducthuntPost struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	PostID   string `gorm:"not null" form:"post_id" json:"post_id"`
+	Name     string `gorm:"not null" form:"name" json:"name"`
+	Language string `form:"language" json:"language"`
+}
+
+type ProducthuntResponse struct {
+	Data struct {
+		Posts struct {
+			Edges []struct {
+				Node ProducthuntPostResult `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"posts"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type ProducthuntPostResult struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Tagline       string    `json:"tagline"`
+	Description   string    `json:"description"`
+	URL           string    `json:"url"`
+	Website       string    `json:"website"`
+	VotesCount    int       `json:"votesCount"`
+	CommentsCount int       `json:"commentsCount"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Thumbnail     struct {
+		URL string `json:"url"`
+	} `json:"thumbnail"`
+	Makers []struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+		URL      string `json:"url"`
+	} `json:"makers"`
+	Topics struct {
+		Edges []struct {
+			Node struct {
+				Name string `json:"name"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"topics"`
+	// Matched is the tag or competitor name the launch mentions
+	Matched string `json:"-"`
+}
+
+// FilterItem converts the launch for evaluation against the filter rules,
+// where the domain is that of the product website
+func (r ProducthuntPostResult) FilterItem() FilterItem {
+	author := ""
+	if len(r.Makers) > 0 {
+		author = r.Makers[0].Username
+	}
+
+	text := strings.Join([]string{r.Name, r.Tagline, r.Description}, " ")
+	return FilterItem{
+		Source:   "producthunt",
+		Text:     text,
+		Author:   author,
+		Language: detectLanguage(text),
+		URL:      r.Website,
+		Links:    []string{r.Website, r.URL},
+		Metrics: map[string]float64{
+			"votes":    float64(r.VotesCount),
+			"comments": float64(r.CommentsCount),
+		},
+	}
+}
+
+// producthuntMatchers returns the matchers a launch is checked against: the
+// configured match expression followed by whole-word matches on competitor
+// names
+func producthuntMatchers(config *Config) ([]*Matcher, error) {
+	competitors := config.ProducthuntCompetitors
+	if len(competitors) == 0 {
+		competitors = defaultCompetitorWords
+	}
+
+	matchers := []*Matcher{config.Matcher}
+	for _, competitor := range competitors {
+		matcher, err := newMatcher("word", competitor, false)
+		if err != nil {
+			return matchers, fmt.Errorf("invalid competitor %q: %w", competitor, err)
+		}
+
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers, nil
+}
+
+// matchProducthuntPost returns the expression of the first matcher the launch
+// matches
+func matchProducthuntPost(result ProducthuntPostResult, matchers []*Matcher) string {
+	for _, matcher := range matchers {
+		if matcher.Match(result.Name, result.Tagline, result.Description, result.Website) {
+			return matcher.expression
+		}
+	}
+
+	return ""
+}
+
+func getProducthuntPosts(config *Config) ([]ProducthuntPostResult, error) {
+	var results []ProducthuntPostResult
+	matchers, err := producthuntMatchers(config)
+	if err != nil {
+		return results, err
+	}
+
+	postedAfter := time.Now().Add(-producthuntLookback).UTC().Format(time.RFC3339)
+	after := ""
+	for page := 1; page <= producthuntMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		variables := map[string]interface{}{
+			"postedAfter": postedAfter,
+		}
+		if after != "" {
+			variables["after"] = after
+		}
+
+		var response ProducthuntResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetAuthToken(config.ProducthuntToken).
+			SetBody(map[string]interface{}{
+				"query":     producthuntPostsQuery,
+				"variables": variables,
+			}).
+			SetResult(&response).
+			Post("https://api.producthunt.com/v2/api/graphql")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error fetching product hunt posts: %s", resp.Status())
+		}
+
+		if len(response.Errors) > 0 {
+			return results, fmt.Errorf("error fetching product hunt posts: %s", response.Errors[0].Message)
+		}
+
+		for _, edge := range response.Data.Posts.Edges {
+			result := edge.Node
+			result.Matched = matchProducthuntPost(result, matchers)
+			if result.Matched == "" {
+				continue
+			}
+
+			results = append(results, result)
+		}
+
+		if !response.Data.Posts.PageInfo.HasNextPage {
+			break
+		}
+		after = response.Data.Posts.PageInfo.EndCursor
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForProducthuntPost(result ProducthuntPostResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"post_id": result.ID,
+		"name":    result.Name,
+	}
+
+	makers := []string{}
+	for _, maker := range result.Makers {
+		makers = append(makers, fmt.Sprintf("<%s|%s>", maker.URL, maker.Name))
+	}
+
+	topics := []string{}
+	for _, edge := range result.Topics.Edges {
+		topics = append(topics, edge.Node.Name)
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "# Votes",
+			Value: strconv.FormatInt(int64(result.VotesCount), 10),
+			Short: true,
+		},
+		{
+			Title: "# Comments",
+			Value: strconv.FormatInt(int64(result.CommentsCount), 10),
+			Short: true,
+		},
+		{
+			Title: "Matched",
+			Value: result.Matched,
+			Short: true,
+		},
+	}
+
+	if len(makers) > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Makers",
+			Value: strings.Join(makers, ", "),
+			Short: true,
+		})
+	}
+
+	if len(topics) > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Topics",
+			Value: strings.Join(topics, ", "),
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New launch on Product Hunt!",
+		Title:      fmt.Sprintf("%s: %s", result.Name, result.Tagline),
+		TitleLink:  result.URL,
+		Text:       result.Description,
+		ThumbURL:   result.Thumbnail.URL,
+		Footer:     "Product Hunt Launch Notification",
+		FooterIcon: producthuntIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.CreatedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":product-hunt:"),
+		slack.MsgOptionText("New launch on <"+result.URL+"|Product Hunt>", false),
+		slack.MsgOptionUsername("Product Hunt Launch Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processProducthunt(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&ProducthuntPost{}); err != nil {
+		return fmt.Errorf("error migrating ProducthuntPost: %w", err)
+	}
+
+	if config.ProducthuntToken == "" {
+		log.Warn("No PRODUCTHUNT_TOKEN specified, skipping producthunt")
+		return nil
+	}
+
+	log.Info("Fetching launches")
+	results, err := getProducthuntPosts(config)
+	if err != nil {
+		return err
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("post_count", len(results)).Info("Processing launches")
+	for _, result := range results {
+		logFields := log.Fields{
+			"post_id": result.ID,
+			"name":    result.Name,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity ProducthuntPost
+		if dbResult := db.First(&entity, "post_id = ?", result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new launch")
+		entity = ProducthuntPost{
+			PostID:   result.ID,
+			Name:     result.Name,
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting launch into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording launch")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForProducthuntPost(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting launch to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for launch")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_post_count": len(results),
+		"inserted_post_count":  inserted,
+		"notified_post_count":  notified,
+	}).Info("Done with product hunt launches")
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var pypiRegistry = Registry{
+	Service:   "pypi",
+	Name:      "PyPI",
+	IconURL:   "https://pypi.org/favicon.ico",
+	IconEmoji: ":python:",
+}
+
+// pypiMaxPages caps how many pages are fetched on a single run
+var pypiMaxPages = 5
+
+// getPypiPackages scrapes the pypi search page, as pypi does not offer a
+// search api
+func getPypiPackages(config *Config) ([]PackageResult, error) {
+	var results []PackageResult
+	for page := 1; page <= pypiMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"q":    config.Tag,
+				"o":    "-created",
+				"page": strconv.FormatInt(int64(page), 10),
+			}).
+			Get("https://pypi.org/search/")
+		if err != nil {
+			return results, err
+		}
+
+		// pypi responds with a 404 once past the last page
+		if resp.StatusCode() == http.StatusNotFound {
+			break
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching pypi packages: %s", resp.Status())
+		}
+
+		document, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body()))
+		if err != nil {
+			return results, fmt.Errorf("error parsing pypi search results: %w", err)
+		}
+
+		snippets := document.Find("a.package-snippet")
+		if snippets.Length() == 0 {
+			break
+		}
+
+		snippets.Each(func(i int, s *goquery.Selection) {
+			name := strings.TrimSpace(s.Find(".package-snippet__name").Text())
+			if name == "" {
+				return
+			}
+
+			description := strings.TrimSpace(s.Find(".package-snippet__description").Text())
+			if !config.Matcher.Verify("pypi", name, name, description) {
+				return
+			}
+
+			publishedAt := time.Time{}
+			if datetime, ok := s.Find(".package-snippet__created time").Attr("datetime"); ok {
+				publishedAt = parseFeedTime(datetime)
+			}
+
+			results = append(results, PackageResult{
+				Registry:    pypiRegistry,
+				Name:        name,
+				Version:     strings.TrimSpace(s.Find(".package-snippet__version").Text()),
+				Description: description,
+				URL:         fmt.Sprintf("https://pypi.org/project/%s/", name),
+				PublishedAt: publishedAt,
+			})
+		})
+	}
+
+	return results, nil
+}
+
+func processPypiPackages(config *Config, db *gorm.DB) error {
+	log.Info("Fetching packages")
+	results, err := getPypiPackages(config)
+	if err != nil {
+		return err
+	}
+
+	return processRegistryPackages(results, config, db)
+}
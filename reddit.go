@@ -1,36 +1,62 @@
 package main
 
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+)
+
 var redditIconURL = "https://emoji.slack-edge.com/T085AJH3L/reddit/42103923a0791a10.png"
 
+// redditMaxPages caps how many pages are fetched per listing on a single run
+var redditMaxPages = 5
+
 type RedditPost struct {
-	ID     int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
-	PostID string `gorm:"not null" form:"post_id" json:"post_id"`
-	Title  string `gorm:"not null" form:"title" json:"title"`
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	PostID   string `gorm:"not null" form:"post_id" json:"post_id"`
+	Kind     string `gorm:"default:t3" form:"kind" json:"kind"`
+	Title    string `gorm:"not null" form:"title" json:"title"`
+	Language string `form:"language" json:"language"`
 }
 
 type RedditResponse struct {
 	Kind string `json:"kind"`
 	Data struct {
-		After     any                `json:"after"`
+		After     string             `json:"after"`
 		Dist      int                `json:"dist"`
 		Modhash   string             `json:"modhash"`
 		GeoFilter string             `json:"geo_filter"`
 		Children  []RedditPostResult `json:"children"`
-		Before    any                `json:"before"`
+		Before    string             `json:"before"`
 	} `json:"data"`
 }
 
+// RedditPostResult is a listing child, which is either a post (kind t3) or a
+// comment (kind t1)
 type RedditPostResult struct {
 	Kind string `json:"kind"`
 	Data struct {
 		ApprovedAtUtc  any     `json:"approved_at_utc"`
 		Author         string  `json:"author"`
 		AuthorFullname string  `json:"author_fullname"`
+		Body           string  `json:"body"`
 		CreatedUtc     float64 `json:"created_utc"`
 		Hidden         bool    `json:"hidden"`
 		ID             string  `json:"id"`
+		LinkAuthor     string  `json:"link_author"`
+		LinkID         string  `json:"link_id"`
+		LinkPermalink  string  `json:"link_permalink"`
+		LinkTitle      string  `json:"link_title"`
 		Name           string  `json:"name"`
+		NumComments    int     `json:"num_comments"`
+		ParentID       string  `json:"parent_id"`
 		Permalink      string  `json:"permalink"`
+		Score          int     `json:"score"`
 		Selftext       string  `json:"selftext"`
 		SelftextHTML   string  `json:"selftext_html"`
 		Subreddit      string  `json:"subreddit"`
@@ -39,3 +65,230 @@ type RedditPostResult struct {
 		URL            string  `json:"url"`
 	} `json:"data,omitempty"`
 }
+
+// IsComment reports whether the listing child is a comment
+func (r RedditPostResult) IsComment() bool {
+	return r.Kind == "t1"
+}
+
+// Link returns the reddit url for the post or comment
+func (r RedditPostResult) Link() string {
+	return fmt.Sprintf("https://www.reddit.com%s", r.Data.Permalink)
+}
+
+// FilterItem converts the post or comment for evaluation against the filter
+// rules, where the domain is that of the link being shared for link posts.
+// Only posts share links, as comments are replies to a post.
+func (r RedditPostResult) FilterItem() FilterItem {
+	link := r.Data.URL
+	if link == "" {
+		link = r.Link()
+	}
+
+	text := strings.Join([]string{r.Data.Title, r.Data.Selftext, r.Data.Body}, " ")
+	item := FilterItem{
+		Source:   "reddit",
+		Text:     text,
+		Author:   r.Data.Author,
+		Language: detectLanguage(text),
+		URL:      link,
+		Metrics: map[string]float64{
+			"score":    float64(r.Data.Score),
+			"comments": float64(r.Data.NumComments),
+		},
+	}
+
+	if !r.IsComment() {
+		item.Links = []string{link, r.Link()}
+	}
+
+	return item
+}
+
+// Mentions reports whether the post or comment matches the match expression
+func (r RedditPostResult) Mentions(matcher *Matcher) bool {
+	return matcher.Verify("reddit", r.Data.Name, r.Data.Title, r.Data.Selftext, r.Data.Body, r.Data.URL)
+}
+
+// RedditAccessTokenResponse is returned when requesting an oauth token
+type RedditAccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// RedditClient talks to the reddit api, using oauth when credentials are
+// configured and the public json endpoints otherwise
+type RedditClient struct {
+	config             *Config
+	client             *resty.Client
+	accessToken        string
+	expiresAt          time.Time
+	rateLimitRemaining float64
+	rateLimitReset     time.Time
+}
+
+func newRedditClient(config *Config) *RedditClient {
+	client := resty.New().
+		SetHeader("User-Agent", config.RedditUserAgent)
+
+	return &RedditClient{
+		config:             config,
+		client:             client,
+		rateLimitRemaining: -1,
+	}
+}
+
+// usesOAuth reports whether the client has credentials to authenticate with
+func (c *RedditClient) usesOAuth() bool {
+	return c.config.RedditClientID != "" && c.config.RedditClientSecret != ""
+}
+
+// authenticate fetches a new access token, using the password grant for
+// script apps when a username is configured and client credentials otherwise
+func (c *RedditClient) authenticate() error {
+	form := map[string]string{
+		"grant_type": "client_credentials",
+	}
+	if c.config.RedditUsername != "" {
+		form = map[string]string{
+			"grant_type": "password",
+			"username":   c.config.RedditUsername,
+			"password":   c.config.RedditPassword,
+		}
+	}
+
+	log.Info("Fetching reddit access token")
+	var response RedditAccessTokenResponse
+	resp, err := c.client.R().
+		SetBasicAuth(c.config.RedditClientID, c.config.RedditClientSecret).
+		SetFormData(form).
+		SetResult(&response).
+		Post("https://www.reddit.com/api/v1/access_token")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() || response.AccessToken == "" {
+		return fmt.Errorf("error fetching reddit access token: %s", resp.Status())
+	}
+
+	c.accessToken = response.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	return nil
+}
+
+// waitForRateLimit sleeps until the rate limit window resets if the previous
+// response said no requests remain
+func (c *RedditClient) waitForRateLimit() {
+	if c.rateLimitRemaining < 0 || c.rateLimitRemaining >= 1 {
+		return
+	}
+
+	wait := time.Until(c.rateLimitReset)
+	if wait <= 0 {
+		return
+	}
+
+	log.WithField("wait", wait.String()).Info("Waiting for reddit rate limit to reset")
+	time.Sleep(wait)
+}
+
+// updateRateLimit records the rate limit state from a response
+func (c *RedditClient) updateRateLimit(resp *resty.Response) {
+	remaining, err := strconv.ParseFloat(resp.Header().Get("X-Ratelimit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseFloat(resp.Header().Get("X-Ratelimit-Reset"), 64)
+	if err != nil {
+		return
+	}
+
+	c.rateLimitRemaining = remaining
+	c.rateLimitReset = time.Now().Add(time.Duration(reset) * time.Second)
+}
+
+// Get fetches the path into result, refreshing the access token when it is
+// about to expire or has been rejected
+func (c *RedditClient) Get(path string, params map[string]string, result interface{}) error {
+	host := "https://www.reddit.com"
+	if c.usesOAuth() {
+		host = "https://oauth.reddit.com"
+		if c.accessToken == "" || time.Until(c.expiresAt) < time.Minute {
+			if err := c.authenticate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		c.waitForRateLimit()
+		request := c.client.R().
+			SetQueryParams(params).
+			SetQueryParam("raw_json", "1").
+			SetResult(result)
+		if c.accessToken != "" {
+			request.SetAuthToken(c.accessToken)
+		}
+
+		resp, err := request.Get(host + path)
+		if err != nil {
+			return err
+		}
+
+		c.updateRateLimit(resp)
+		if resp.StatusCode() == http.StatusUnauthorized && c.usesOAuth() && attempt == 0 {
+			if err := c.authenticate(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp.IsError() {
+			return fmt.Errorf("error fetching reddit listing %s: %s", path, resp.Status())
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// getRedditListing fetches every page of a listing, following the after
+// cursor until it runs out or the page cap is hit
+func getRedditListing(client *RedditClient, path string, params map[string]string) ([]RedditPostResult, error) {
+	var results []RedditPostResult
+	after := ""
+	for page := 1; page <= redditMaxPages; page++ {
+		log.WithFields(log.Fields{
+			"path":  path,
+			"after": after,
+		}).Info("Fetching page")
+
+		query := map[string]string{
+			"limit": "100",
+		}
+		for key, value := range params {
+			query[key] = value
+		}
+		if after != "" {
+			query["after"] = after
+		}
+
+		var response RedditResponse
+		if err := client.Get(path, query, &response); err != nil {
+			return results, err
+		}
+
+		results = append(results, response.Data.Children...)
+		if response.Data.After == "" || len(response.Data.Children) == 0 {
+			break
+		}
+		after = response.Data.After
+	}
+
+	return results, nil
+}
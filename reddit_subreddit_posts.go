@@ -5,84 +5,93 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
-	"github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
 	"github.com/slack-go/slack"
 	"gorm.io/gorm"
 )
 
-func getRedditPosts(config *Config) ([]RedditPostResult, error) {
+// redditSubreddits returns the subreddits to watch, falling back to the tag
+// for deployments that predate the REDDIT_SUBREDDITS setting
+func redditSubreddits(config *Config) []string {
+	if len(config.RedditSubreddits) > 0 {
+		return config.RedditSubreddits
+	}
+
+	return []string{config.Tag}
+}
+
+func getRedditPosts(client *RedditClient, config *Config) ([]RedditPostResult, error) {
 	var results []RedditPostResult
-	var response RedditResponse
-	client := resty.New()
-	_, err := client.R().
-		SetHeaders(map[string]string{
-			"authority":                 "www.reddit.com",
-			"pragma":                    "no-cache",
-			"cache-control":             "no-cache",
-			"sec-ch-ua":                 `"Google Chrome";v="89", "Chromium";v="89", ";Not A Brand";v="99"`,
-			"sec-ch-ua-mobile":          "?0",
-			"upgrade-insecure-requests": "1",
-			"user-agent":                "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/89.0.4389.90 Safari/537.36",
-			"accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9",
-			"dnt":                       "1",
-			"sec-fetch-site":            "none",
-			"sec-fetch-mode":            "navigate",
-			"sec-fetch-user":            "?1",
-			"sec-fetch-dest":            "document",
-			"accept-language":           "en-GB,en;q=0.9",
-		}).
-		SetResult(&response).
-		Get(fmt.Sprintf("https://www.reddit.com/r/%s/new.json?limit=100", config.Tag))
+	subreddits := strings.Join(redditSubreddits(config), "+")
+	posts, err := getRedditListing(client, fmt.Sprintf("/r/%s/new.json", subreddits), map[string]string{})
 	if err != nil {
 		return results, err
 	}
 
-	results = append(results, response.Data.Children...)
+	results = append(results, posts...)
+
+	// every post in a watched subreddit is relevant, but comments only
+	// are when they mention the tag
+	comments, err := getRedditListing(client, fmt.Sprintf("/r/%s/comments.json", subreddits), map[string]string{})
+	if err != nil {
+		return results, err
+	}
+
+	for _, comment := range comments {
+		if comment.Mentions(config.Matcher) {
+			results = append(results, comment)
+		}
+	}
 
 	return results, nil
 }
 
-func getRedditSearchPosts(config *Config) ([]RedditPostResult, error) {
+// getRedditSearchPosts searches all of reddit for posts mentioning the tag.
+// Search only returns posts, so comments are only found in the comment
+// listings of the watched subreddits.
+func getRedditSearchPosts(client *RedditClient, config *Config) ([]RedditPostResult, error) {
 	var results []RedditPostResult
-	var response RedditResponse
-	client := resty.New()
-	_, err := client.R().
-		SetHeaders(map[string]string{
-			"authority":                 "www.reddit.com",
-			"pragma":                    "no-cache",
-			"cache-control":             "no-cache",
-			"sec-ch-ua":                 `"Google Chrome";v="89", "Chromium";v="89", ";Not A Brand";v="99"`,
-			"sec-ch-ua-mobile":          "?0",
-			"upgrade-insecure-requests": "1",
-			"user-agent":                "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/89.0.4389.90 Safari/537.36",
-			"accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9",
-			"dnt":                       "1",
-			"sec-fetch-site":            "none",
-			"sec-fetch-mode":            "navigate",
-			"sec-fetch-user":            "?1",
-			"sec-fetch-dest":            "document",
-			"accept-language":           "en-GB,en;q=0.9",
-		}).
-		SetResult(&response).
-		Get(fmt.Sprintf("https://www.reddit.com/search.json?q=%s&type=comment&sort=updated?limit=100", config.Tag))
+	children, err := getRedditListing(client, "/search.json", map[string]string{
+		"q":    config.Tag,
+		"sort": "new",
+		"type": "link",
+	})
 	if err != nil {
 		return results, err
 	}
 
-	results = append(results, response.Data.Children...)
+	for _, child := range children {
+		if child.Mentions(config.Matcher) {
+			results = append(results, child)
+		}
+	}
 
 	return results, nil
 }
+
 func sendSlackNotificationForRedditPost(result RedditPostResult, config *Config) error {
 	if !config.NotifySlack {
 		return nil
 	}
 
 	logFields := log.Fields{
-		"article_id": result.Data.ID,
-		"title":      result.Data.Title,
+		"post_id": result.Data.ID,
+		"title":   result.Data.Title,
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Subreddit",
+			Value: fmt.Sprintf("r/%s", result.Data.Subreddit),
+			Short: true,
+		},
+		{
+			Title: "# Comments",
+			Value: strconv.FormatInt(int64(result.Data.NumComments), 10),
+			Short: true,
+		},
 	}
 
 	attachment := slack.Attachment{
@@ -96,6 +105,7 @@ func sendSlackNotificationForRedditPost(result RedditPostResult, config *Config)
 		Footer:     "Reddit Post Notification",
 		FooterIcon: redditIconURL,
 		Ts:         json.Number(strconv.FormatInt(int64(result.Data.CreatedUtc), 10)),
+		Fields:     fields,
 	}
 
 	log.WithFields(logFields).Info("Notifying slack")
@@ -103,17 +113,71 @@ func sendSlackNotificationForRedditPost(result RedditPostResult, config *Config)
 		slack.MsgOptionAsUser(false),
 		slack.MsgOptionAttachments(attachment),
 		slack.MsgOptionIconEmoji(":reddit:"),
-		slack.MsgOptionText("New post on <"+result.Data.URL+"|Reddit>", false),
+		slack.MsgOptionText("New post on <"+result.Link()+"|Reddit>", false),
 		slack.MsgOptionUsername("Reddit Post Notifications"),
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
+	return postSlackMessage(config, messageOpts...)
+}
+
+func sendSlackNotificationForRedditComment(result RedditPostResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
 	}
 
-	return nil
+	logFields := log.Fields{
+		"comment_id": result.Data.ID,
+		"post_title": result.Data.LinkTitle,
+	}
+
+	link := result.Link()
+	fields := []slack.AttachmentField{
+		{
+			Title: "Subreddit",
+			Value: fmt.Sprintf("r/%s", result.Data.Subreddit),
+			Short: true,
+		},
+		{
+			Title: "# Points",
+			Value: strconv.FormatInt(int64(result.Data.Score), 10),
+			Short: true,
+		},
+	}
+
+	if len(result.Data.LinkPermalink) > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Parent Post",
+			Value: fmt.Sprintf("<%s|%s>", result.Data.LinkPermalink, result.Data.LinkTitle),
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New comment on Reddit!",
+		AuthorName: result.Data.Author,
+		AuthorLink: fmt.Sprintf("https://www.reddit.com/user/%s", result.Data.Author),
+		Title:      fmt.Sprintf("Comment on: %s", result.Data.LinkTitle),
+		TitleLink:  link,
+		Text:       result.Data.Body,
+		Footer:     "Reddit Comment Notification",
+		FooterIcon: redditIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.Data.CreatedUtc), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":reddit:"),
+		slack.MsgOptionText("New comment on <"+link+"|Reddit>", false),
+		slack.MsgOptionUsername("Reddit Comment Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
 }
 
 func processRedditPosts(config *Config, db *gorm.DB) error {
@@ -122,12 +186,13 @@ func processRedditPosts(config *Config, db *gorm.DB) error {
 	}
 
 	log.Info("Fetching posts")
-	results, err := getRedditPosts(config)
+	client := newRedditClient(config)
+	results, err := getRedditPosts(client, config)
 	if err != nil {
 		return err
 	}
 
-	searchResults, err := getRedditSearchPosts(config)
+	searchResults, err := getRedditSearchPosts(client, config)
 	if err != nil {
 		return err
 	}
@@ -140,18 +205,31 @@ func processRedditPosts(config *Config, db *gorm.DB) error {
 	for _, result := range results {
 		logFields := log.Fields{
 			"post_id": result.Data.ID,
+			"kind":    result.Kind,
 			"title":   result.Data.Title,
 		}
 
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
 		var entity RedditPost
-		if dbResult := db.First(&entity, "post_id = ?", result.Data.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+		if dbResult := db.First(&entity, "post_id = ? AND kind = ?", result.Data.ID, result.Kind); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
 			continue
 		}
 
+		title := result.Data.Title
+		if result.IsComment() {
+			title = result.Data.LinkTitle
+		}
+
 		log.WithFields(logFields).Info("Inserting new post")
 		entity = RedditPost{
-			PostID: result.Data.ID,
-			Title:  result.Data.Title,
+			PostID:   result.Data.ID,
+			Kind:     result.Kind,
+			Title:    title,
+			Language: item.Language,
 		}
 
 		if dbResult := db.Create(&entity); dbResult.Error != nil {
@@ -160,11 +238,30 @@ func processRedditPosts(config *Config, db *gorm.DB) error {
 		}
 
 		inserted += 1
-		if err := sendSlackNotificationForRedditPost(result, config); err != nil {
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording post")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		notify := sendSlackNotificationForRedditPost
+		if result.IsComment() {
+			notify = sendSlackNotificationForRedditComment
+		}
+
+		if err := notify(result, notifyConfig); err != nil {
 			log.WithError(err).WithFields(logFields).Fatal("error posting post to slack")
 			continue
 		}
 
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for post")
+			continue
+		}
+
 		notified += 1
 	}
 	log.WithFields(log.Fields{
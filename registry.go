@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+// Registry describes a package registry for notification purposes
+type Registry struct {
+	// Service is the name the registry's service is run under, which filter
+	// rules refer to
+	Service   string
+	Name      string
+	IconURL   string
+	IconEmoji string
+}
+
+type RegistryPackage struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Registry string `gorm:"not null" form:"registry" json:"registry"`
+	Name     string `gorm:"not null" form:"name" json:"name"`
+	Version  string `form:"version" json:"version"`
+	Language string `form:"language" json:"language"`
+}
+
+// PackageResult is the shape package notifications are rendered from,
+// regardless of which registry the package is published to
+type PackageResult struct {
+	Registry    Registry
+	Name        string
+	Version     string
+	Description string
+	Author      string
+	AuthorURL   string
+	URL         string
+	Downloads   int
+	PublishedAt time.Time
+}
+
+// FilterItem converts the package for evaluation against the filter rules
+func (r PackageResult) FilterItem() FilterItem {
+	return FilterItem{
+		Source:   r.Registry.Service,
+		Text:     strings.Join([]string{r.Name, r.Description}, " "),
+		Author:   r.Author,
+		Language: detectLanguage(r.Description),
+		URL:      r.URL,
+		Links:    []string{r.URL},
+		Metrics: map[string]float64{
+			"downloads": float64(r.Downloads),
+		},
+	}
+}
+
+func sendSlackNotificationForPackage(result PackageResult, isNewVersion bool, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"registry": result.Registry.Name,
+		"name":     result.Name,
+		"version":  result.Version,
+	}
+
+	kind := "package"
+	if isNewVersion {
+		kind = "version"
+	}
+
+	fields := []slack.AttachmentField{}
+	if len(result.Version) > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Version",
+			Value: result.Version,
+			Short: true,
+		})
+	}
+
+	if result.Downloads > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "# Downloads",
+			Value: strconv.FormatInt(int64(result.Downloads), 10),
+			Short: true,
+		})
+	}
+
+	ts := result.PublishedAt
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   fmt.Sprintf("New %s on %s!", kind, result.Registry.Name),
+		AuthorName: result.Author,
+		AuthorLink: result.AuthorURL,
+		Title:      result.Name,
+		TitleLink:  result.URL,
+		Text:       result.Description,
+		Footer:     fmt.Sprintf("%s Package Notification", result.Registry.Name),
+		FooterIcon: result.Registry.IconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(ts.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(result.Registry.IconEmoji),
+		slack.MsgOptionText(fmt.Sprintf("New %s on <%s|%s>", kind, result.URL, result.Registry.Name), false),
+		slack.MsgOptionUsername(fmt.Sprintf("%s Package Notifications", result.Registry.Name)),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+// processRegistryPackages records and notifies on new packages, and on new
+// versions of known packages when REGISTRY_NOTIFY_VERSIONS is enabled
+func processRegistryPackages(results []PackageResult, config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&RegistryPackage{}); err != nil {
+		return fmt.Errorf("error migrating RegistryPackage: %w", err)
+	}
+
+	inserted := 0
+	updated := 0
+	notified := 0
+	log.WithField("package_count", len(results)).Info("Processing packages")
+	for _, result := range results {
+		logFields := log.Fields{
+			"registry": result.Registry.Name,
+			"name":     result.Name,
+			"version":  result.Version,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity RegistryPackage
+		dbResult := db.First(&entity, "registry = ? AND name = ?", result.Registry.Name, result.Name)
+		if dbResult.Error != nil && !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("error fetching package: %w", dbResult.Error)
+		}
+
+		isNewVersion := false
+		if dbResult.Error == nil {
+			if result.Version == "" || entity.Version == result.Version {
+				continue
+			}
+
+			log.WithFields(logFields).Info("Updating package version")
+			previousVersion := entity.Version
+			entity.Version = result.Version
+			if dbResult := db.Save(&entity); dbResult.Error != nil {
+				log.WithError(dbResult.Error).WithFields(logFields).Fatal("error updating package in database")
+				continue
+			}
+
+			updated += 1
+			// a package recorded without a version has nothing to compare
+			// against, so the first version seen is not announced
+			if !config.RegistryNotifyVersions || previousVersion == "" {
+				continue
+			}
+			isNewVersion = true
+		} else {
+			log.WithFields(logFields).Info("Inserting new package")
+			entity = RegistryPackage{
+				Registry: result.Registry.Name,
+				Name:     result.Name,
+				Version:  result.Version,
+				Language: item.Language,
+			}
+
+			if dbResult := db.Create(&entity); dbResult.Error != nil {
+				log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting package into database")
+				continue
+			}
+
+			inserted += 1
+		}
+
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording package")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForPackage(result, isNewVersion, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting package to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for package")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_package_count": len(results),
+		"inserted_package_count":  inserted,
+		"updated_package_count":   updated,
+		"notified_package_count":  notified,
+	}).Info("Done with registry packages")
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+// Forge describes where a repository is hosted for notification purposes
+type Forge struct {
+	// Service is the name the forge's service is run under, which filter
+	// rules refer to
+	Service   string
+	Name      string
+	IconURL   string
+	IconEmoji string
+}
+
+var githubForge = Forge{
+	Service:   "github",
+	Name:      "Github",
+	IconURL:   githubIconURL,
+	IconEmoji: ":github:",
+}
+
+type ForgeRepository struct {
+	ID           int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Host         string `gorm:"not null" form:"host" json:"host"`
+	RepositoryID int64  `gorm:"not null" form:"repository_id" json:"repository_id"`
+	Title        string `gorm:"not null" form:"title" json:"title"`
+	Language     string `form:"language" json:"language"`
+}
+
+// RepositoryResult is the shape repository notifications are rendered from,
+// regardless of which forge the repository is hosted on
+type RepositoryResult struct {
+	Forge          Forge
+	Host           string
+	RepositoryID   int64
+	FullName       string
+	Description    string
+	URL            string
+	OwnerLogin     string
+	OwnerAvatarURL string
+	OwnerURL       string
+	Language       string
+	Stars          int
+	CreatedAt      time.Time
+}
+
+// FilterItem converts the repository for evaluation against the filter rules
+func (r RepositoryResult) FilterItem() FilterItem {
+	return FilterItem{
+		Source:   r.Forge.Service,
+		Text:     strings.Join([]string{r.FullName, r.Description}, " "),
+		Author:   r.OwnerLogin,
+		Language: detectLanguage(r.Description),
+		URL:      r.URL,
+		Links:    []string{r.URL},
+		Metrics: map[string]float64{
+			"stars": float64(r.Stars),
+		},
+	}
+}
+
+func sendSlackNotificationForRepository(result RepositoryResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"forge":         result.Forge.Name,
+		"repository_id": result.RepositoryID,
+	}
+
+	fields := []slack.AttachmentField{
+		{
+			Title: "Language",
+			Value: result.Language,
+			Short: true,
+		},
+		{
+			Title: "# Stars",
+			Value: strconv.FormatInt(int64(result.Stars), 10),
+			Short: true,
+		},
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   fmt.Sprintf("New repository on %s!", result.Forge.Name),
+		AuthorName: result.OwnerLogin,
+		AuthorIcon: result.OwnerAvatarURL,
+		AuthorLink: result.OwnerURL,
+		Title:      result.FullName,
+		TitleLink:  result.URL,
+		Footer:     fmt.Sprintf("%s Repository Notification", result.Forge.Name),
+		FooterIcon: result.Forge.IconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.CreatedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(result.Forge.IconEmoji),
+		slack.MsgOptionText(fmt.Sprintf("New repository on <%s|%s>", result.URL, result.Forge.Name), false),
+		slack.MsgOptionUsername(fmt.Sprintf("%s Repository Notifications", result.Forge.Name)),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+// processForgeRepositories records and notifies on repositories from forges
+// other than github. enrich, when set, is called for new repositories only,
+// to fill in details that require additional requests.
+func processForgeRepositories(results []RepositoryResult, enrich func(RepositoryResult) (RepositoryResult, error), config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&ForgeRepository{}); err != nil {
+		return fmt.Errorf("error migrating ForgeRepository: %w", err)
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("repository_count", len(results)).Info("Processing repositories")
+	for _, result := range results {
+		logFields := log.Fields{
+			"host":          result.Host,
+			"repository_id": result.RepositoryID,
+			"title":         result.FullName,
+		}
+
+		if !config.Filters.Allow(result.FilterItem()) {
+			continue
+		}
+
+		var entity ForgeRepository
+		if dbResult := db.First(&entity, "host = ? AND repository_id = ?", result.Host, result.RepositoryID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		if enrich != nil {
+			var err error
+			result, err = enrich(result)
+			if err != nil {
+				return err
+			}
+		}
+
+		item := result.FilterItem()
+		log.WithFields(logFields).Info("Inserting new repository")
+		entity = ForgeRepository{
+			Host:         result.Host,
+			RepositoryID: result.RepositoryID,
+			Title:        result.FullName,
+			Language:     item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting repository into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording repository")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForRepository(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting repository to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for repository")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_repository_count": len(results),
+		"inserted_repository_count":  inserted,
+		"notified_repository_count":  notified,
+	}).Info("Done with forge repositories")
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/slack-go/slack"
+)
+
+// SlackThread is the thread a notification is posted to. When the thread has
+// not been started, the notification starts it, and the thread records the
+// message that was posted so that later notifications can reply to it.
+type SlackThread struct {
+	ChannelID string
+	TS        string
+
+	// links are the canonical urls shared by the mention being notified
+	// about, which are recorded against the thread once it is posted
+	links     []string
+	mentionID int32
+	source    string
+}
+
+// postSlackMessage posts a notification to the configured channel, or as a
+// reply when the config carries a thread that has already been started
+func postSlackMessage(config *Config, messageOpts ...slack.MsgOption) error {
+	channelID := config.SlackChannelID
+	if config.SlackThread != nil && config.SlackThread.TS != "" {
+		channelID = config.SlackThread.ChannelID
+		messageOpts = append(messageOpts, slack.MsgOptionTS(config.SlackThread.TS))
+	}
+
+	api := slack.New(config.SlackToken)
+	channel, ts, err := api.PostMessage(channelID, messageOpts...)
+	if err != nil {
+		return err
+	}
+
+	if config.SlackThread != nil && config.SlackThread.TS == "" {
+		config.SlackThread.ChannelID = channel
+		config.SlackThread.TS = ts
+	}
+
+	return nil
+}
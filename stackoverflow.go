@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/antihax/optional"
+	"github.com/go-resty/resty/v2"
 	stackoverflow "github.com/grokify/go-stackoverflow/client"
 	"github.com/grokify/go-stackoverflow/util"
 	log "github.com/sirupsen/logrus"
@@ -17,32 +21,416 @@ import (
 
 var stackoverflowIconURL = "https://emoji.slack-edge.com/T085AJH3L/stackoverflow/35cab7f857fa4681.png"
 
+// stackexchangeFollowDays is how long answers and comments are followed on a
+// question after it has been announced
+var stackexchangeFollowDays = 30
+
+// stackexchangeMaxPages caps how many pages are fetched per free-text search
+// or batch of followed posts on a single run
+var stackexchangeMaxPages = 5
+
+// stackexchangeLookback is how far back questions are fetched, so that a
+// newly added site or tag only announces recent questions rather than its
+// whole history
+var stackexchangeLookback = 7 * 24 * time.Hour
+
+var stackexchangeSiteNames = map[string]string{
+	"askubuntu":     "Ask Ubuntu",
+	"devops":        "DevOps",
+	"serverfault":   "Server Fault",
+	"stackoverflow": "StackOverflow",
+	"superuser":     "Super User",
+}
+
+// Question was used to track stackoverflow questions before multiple sites
+// were supported, and is only read to migrate to StackexchangeQuestion
 type Question struct {
 	ID    int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
 	Title string `gorm:"not null" form:"title" json:"title"`
 }
 
-func getQuestions(config *Config) ([]stackoverflow.Question, error) {
-	questions, err := util.GetQuestionsAll(nil, config.Site, &stackoverflow.GetQuestionsOpts{
-		Tagged:   optional.NewString(config.Tag),
+type StackexchangeQuestion struct {
+	ID          int32      `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Site        string     `gorm:"not null" form:"site" json:"site"`
+	QuestionID  int32      `gorm:"not null" form:"question_id" json:"question_id"`
+	Title       string     `gorm:"not null" form:"title" json:"title"`
+	Link        string     `form:"link" json:"link"`
+	AnnouncedAt *time.Time `form:"announced_at" json:"announced_at"`
+	Language    string     `form:"language" json:"language"`
+}
+
+type StackexchangePost struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Site     string `gorm:"not null" form:"site" json:"site"`
+	PostID   int32  `gorm:"not null" form:"post_id" json:"post_id"`
+	Kind     string `gorm:"not null" form:"kind" json:"kind"`
+	Language string `form:"language" json:"language"`
+}
+
+// StackexchangeQuestionsResponse is a page of questions, which include their
+// body so that matches in the body can be verified
+type StackexchangeQuestionsResponse struct {
+	Items []struct {
+		stackoverflow.Question
+		Body string `json:"body"`
+	} `json:"items"`
+	HasMore        bool `json:"has_more"`
+	QuotaMax       int  `json:"quota_max"`
+	QuotaRemaining int  `json:"quota_remaining"`
+	Backoff        int  `json:"backoff"`
+}
+
+type StackexchangePostsResponse struct {
+	Items          []StackexchangePostResult `json:"items"`
+	HasMore        bool                      `json:"has_more"`
+	QuotaMax       int                       `json:"quota_max"`
+	QuotaRemaining int                       `json:"quota_remaining"`
+	Backoff        int                       `json:"backoff"`
+}
+
+// StackexchangePostResult is either an answer or a comment, depending on
+// which of AnswerID or CommentID is set
+type StackexchangePostResult struct {
+	AnswerID     int32                       `json:"answer_id"`
+	CommentID    int32                       `json:"comment_id"`
+	PostID       int32                       `json:"post_id"`
+	QuestionID   int32                       `json:"question_id"`
+	Owner        stackoverflow.QuestionOwner `json:"owner"`
+	Score        int32                       `json:"score"`
+	IsAccepted   bool                        `json:"is_accepted"`
+	CreationDate int64                       `json:"creation_date"`
+	Body         string                      `json:"body"`
+}
+
+// StackexchangeSiteResult pairs an answer or comment with the question it
+// was posted on
+type StackexchangeSiteResult struct {
+	Site     string
+	Question StackexchangeQuestion
+	Post     StackexchangePostResult
+}
+
+// Kind returns whether the post is an answer or a comment
+func (r StackexchangeSiteResult) Kind() string {
+	if r.Post.CommentID != 0 {
+		return "comment"
+	}
+
+	return "answer"
+}
+
+// PostID returns the answer or comment id
+func (r StackexchangeSiteResult) PostID() int32 {
+	if r.Post.CommentID != 0 {
+		return r.Post.CommentID
+	}
+
+	return r.Post.AnswerID
+}
+
+// Link returns the url to the answer or comment, built from the link of the
+// question it was posted on
+func (r StackexchangeSiteResult) Link() string {
+	u, err := url.Parse(r.Question.Link)
+	if err != nil {
+		return r.Question.Link
+	}
+
+	if r.Post.CommentID != 0 {
+		return fmt.Sprintf("%s://%s/questions/%d#comment%d_%d", u.Scheme, u.Host, r.Question.QuestionID, r.Post.CommentID, r.Post.PostID)
+	}
+
+	return fmt.Sprintf("%s://%s/a/%d", u.Scheme, u.Host, r.Post.AnswerID)
+}
+
+// FilterItem converts the answer or comment for evaluation against the
+// filter rules
+func (r StackexchangeSiteResult) FilterItem() FilterItem {
+	text := strings.Join([]string{r.Question.Title, stripFeedHTML(r.Post.Body)}, " ")
+	return FilterItem{
+		Source:   "stackoverflow",
+		Text:     text,
+		Author:   r.Post.Owner.DisplayName,
+		Language: detectLanguage(text),
+		URL:      r.Link(),
+		Metrics: map[string]float64{
+			"score": float64(r.Post.Score),
+		},
+	}
+}
+
+// stackoverflowQuestionFilterItem converts a question for evaluation against
+// the filter rules
+func stackoverflowQuestionFilterItem(question stackoverflow.Question) FilterItem {
+	return FilterItem{
+		Source:   "stackoverflow",
+		Text:     strings.Join(append([]string{question.Title}, question.Tags...), " "),
+		Author:   question.Owner.DisplayName,
+		Language: detectLanguage(question.Title),
+		URL:      question.Link,
+		Links:    []string{question.Link},
+		Metrics: map[string]float64{
+			"score":   float64(question.Score),
+			"answers": float64(question.AnswerCount),
+			"views":   float64(question.ViewCount),
+		},
+	}
+}
+
+// stackexchangeSiteName returns the display name for a site
+func stackexchangeSiteName(site string) string {
+	if name, ok := stackexchangeSiteNames[site]; ok {
+		return name
+	}
+
+	return site
+}
+
+// stackexchangeSites returns the sites to monitor, falling back to the
+// single SITE setting
+func stackexchangeSites(config *Config) []string {
+	if len(config.StackexchangeSites) > 0 {
+		return config.StackexchangeSites
+	}
+
+	if config.Site != "" {
+		return []string{config.Site}
+	}
+
+	return []string{util.SiteStackOverflow}
+}
+
+// stackexchangeTags returns the tags to monitor on a site, which are
+// separated by semicolons as commas separate sites
+func stackexchangeTags(site string, config *Config) []string {
+	tags, ok := config.StackexchangeTags[site]
+	if !ok {
+		return []string{config.Tag}
+	}
+
+	return strings.Split(tags, ";")
+}
+
+// getStackexchange fetches a path from the stack exchange api, honoring any
+// backoff the api asks for
+func getStackexchange(path string, params map[string]string, result interface{}, config *Config) error {
+	client := resty.New()
+	request := client.R().
+		SetQueryParams(params).
+		SetResult(result)
+	if config.StackexchangeKey != "" {
+		request.SetQueryParam("key", config.StackexchangeKey)
+	}
+
+	resp, err := request.Get(fmt.Sprintf("https://api.stackexchange.com/2.3%s", path))
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("error fetching %s from stack exchange: %s", path, resp.Status())
+	}
+
+	var backoff struct {
+		Backoff int `json:"backoff"`
+	}
+	if err := json.Unmarshal(resp.Body(), &backoff); err == nil && backoff.Backoff > 0 {
+		log.WithField("backoff", backoff.Backoff).Info("Backing off stack exchange")
+		time.Sleep(time.Duration(backoff.Backoff) * time.Second)
+	}
+
+	return nil
+}
+
+func getQuestions(site string, tag string) ([]stackoverflow.Question, error) {
+	questions, err := util.GetQuestionsAll(nil, site, &stackoverflow.GetQuestionsOpts{
+		Fromdate: optional.NewInt32(int32(time.Now().Add(-stackexchangeLookback).Unix())),
+		Tagged:   optional.NewString(tag),
 		Page:     optional.NewInt32(1),
 		Pagesize: optional.NewInt32(int32(util.PerPageMax)),
 		Sort:     optional.NewString("creation"),
 		Order:    optional.NewString("asc"),
 	})
 	if err != nil {
-		return questions, fmt.Errorf("error fetching questions from stackoverflow: %w", err)
+		return questions, fmt.Errorf("error fetching questions from %s: %w", site, err)
+	}
+
+	return questions, nil
+}
+
+func searchQuestions(site string, query string, config *Config) ([]stackoverflow.Question, error) {
+	var questions []stackoverflow.Question
+	for page := 1; page <= stackexchangeMaxPages; page++ {
+		log.WithFields(log.Fields{
+			"site":  site,
+			"query": query,
+			"page":  page,
+		}).Info("Fetching page")
+
+		var response StackexchangeQuestionsResponse
+		err := getStackexchange("/search/advanced", map[string]string{
+			"q":        query,
+			"site":     site,
+			"sort":     "creation",
+			"order":    "desc",
+			"fromdate": strconv.FormatInt(time.Now().Add(-stackexchangeLookback).Unix(), 10),
+			"pagesize": "100",
+			"page":     strconv.FormatInt(int64(page), 10),
+			"filter":   "withbody",
+		}, &response, config)
+		if err != nil {
+			return questions, err
+		}
+
+		for _, item := range response.Items {
+			values := append([]string{item.Title, stripFeedHTML(item.Body)}, item.Tags...)
+			if !config.Matcher.Verify("stackoverflow", strconv.FormatInt(int64(item.QuestionId), 10), values...) {
+				continue
+			}
+
+			questions = append(questions, item.Question)
+		}
+		if !response.HasMore {
+			break
+		}
 	}
 
 	return questions, nil
 }
 
-func sendSlackNotificationForStackoverflow(question stackoverflow.Question, config *Config) error {
+// getStackexchangePosts fetches the answers or comments at the path, newest
+// first, following has_more until the page cap is reached
+func getStackexchangePosts(path string, site string, config *Config) ([]StackexchangePostResult, error) {
+	var posts []StackexchangePostResult
+	for page := 1; page <= stackexchangeMaxPages; page++ {
+		var response StackexchangePostsResponse
+		err := getStackexchange(path, map[string]string{
+			"site":     site,
+			"sort":     "creation",
+			"order":    "desc",
+			"pagesize": "100",
+			"page":     strconv.FormatInt(int64(page), 10),
+			"filter":   "withbody",
+		}, &response, config)
+		if err != nil {
+			return posts, err
+		}
+
+		posts = append(posts, response.Items...)
+		if !response.HasMore {
+			break
+		}
+	}
+
+	return posts, nil
+}
+
+// stackexchangeIDBatches joins the ids into semicolon-separated batches of
+// up to 100, the most the api accepts per request
+func stackexchangeIDBatches(ids []int32) []string {
+	var batches []string
+	for start := 0; start < len(ids); start += 100 {
+		end := min(start+100, len(ids))
+		var batch []string
+		for _, id := range ids[start:end] {
+			batch = append(batch, strconv.FormatInt(int64(id), 10))
+		}
+		batches = append(batches, strings.Join(batch, ";"))
+	}
+
+	return batches
+}
+
+// getFollowedPosts fetches answers and comments on questions announced
+// within the follow window, including comments on the answers to them
+func getFollowedPosts(site string, config *Config, db *gorm.DB) ([]StackexchangeSiteResult, error) {
+	var results []StackexchangeSiteResult
+	var questions []StackexchangeQuestion
+	since := time.Now().AddDate(0, 0, -stackexchangeFollowDays)
+	if dbResult := db.Find(&questions, "site = ? AND announced_at > ?", site, since); dbResult.Error != nil {
+		return results, fmt.Errorf("error fetching followed questions: %w", dbResult.Error)
+	}
+
+	var questionIDs []int32
+	byID := map[int32]StackexchangeQuestion{}
+	for _, question := range questions {
+		questionIDs = append(questionIDs, question.QuestionID)
+		byID[question.QuestionID] = question
+	}
+
+	// comments only carry the id of the post they were made on, so every
+	// answer is mapped back to its question, including answers posted
+	// before the question was announced, as those can get new comments too
+	var answerIDs []int32
+	answerQuestions := map[int32]int32{}
+	var posts []StackexchangePostResult
+	for _, ids := range stackexchangeIDBatches(questionIDs) {
+		for _, kind := range []string{"answers", "comments"} {
+			log.WithFields(log.Fields{
+				"site": site,
+				"kind": kind,
+			}).Info("Fetching followed posts")
+
+			kindPosts, err := getStackexchangePosts(fmt.Sprintf("/questions/%s/%s", ids, kind), site, config)
+			if err != nil {
+				return results, err
+			}
+
+			for _, post := range kindPosts {
+				if post.CommentID == 0 {
+					answerIDs = append(answerIDs, post.AnswerID)
+					answerQuestions[post.AnswerID] = post.QuestionID
+				}
+			}
+			posts = append(posts, kindPosts...)
+		}
+	}
+
+	for _, ids := range stackexchangeIDBatches(answerIDs) {
+		log.WithFields(log.Fields{
+			"site": site,
+			"kind": "answer comments",
+		}).Info("Fetching followed posts")
+
+		comments, err := getStackexchangePosts(fmt.Sprintf("/answers/%s/comments", ids), site, config)
+		if err != nil {
+			return results, err
+		}
+		posts = append(posts, comments...)
+	}
+
+	for _, post := range posts {
+		questionID := post.QuestionID
+		if post.CommentID != 0 {
+			questionID = post.PostID
+			if answerQuestionID, ok := answerQuestions[post.PostID]; ok {
+				questionID = answerQuestionID
+			}
+		}
+
+		// only posts made after the question was announced are new
+		question := byID[questionID]
+		if question.AnnouncedAt == nil || post.CreationDate <= question.AnnouncedAt.Unix() {
+			continue
+		}
+
+		results = append(results, StackexchangeSiteResult{
+			Site:     site,
+			Question: question,
+			Post:     post,
+		})
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForStackoverflow(site string, question stackoverflow.Question, config *Config) error {
 	if !config.NotifySlack {
 		return nil
 	}
 
 	logFields := log.Fields{
+		"site":        site,
 		"question_id": question.QuestionId,
 		"title":       question.Title,
 	}
@@ -54,7 +442,7 @@ func sendSlackNotificationForStackoverflow(question stackoverflow.Question, conf
 
 	attachment := slack.Attachment{
 		Color:      "#36a64f",
-		Fallback:   "New question on StackOverflow!",
+		Fallback:   fmt.Sprintf("New question on %s!", stackexchangeSiteName(site)),
 		AuthorName: question.Owner.DisplayName,
 		AuthorLink: question.Owner.Link,
 		AuthorIcon: question.Owner.ProfileImage,
@@ -92,67 +480,283 @@ func sendSlackNotificationForStackoverflow(question stackoverflow.Question, conf
 		slack.MsgOptionAsUser(false),
 		slack.MsgOptionAttachments(attachment),
 		slack.MsgOptionIconEmoji(":stackoverflow:"),
-		slack.MsgOptionText("New question on <"+question.Link+"|StackOverflow>", false),
+		slack.MsgOptionText(fmt.Sprintf("New question on <%s|%s>", question.Link, stackexchangeSiteName(site)), false),
 		slack.MsgOptionUsername("Stackoverflow Notification"),
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		println(err.Error())
+	return postSlackMessage(config, messageOpts...)
+}
+
+func sendSlackNotificationForStackexchangePost(result StackexchangeSiteResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"site":        result.Site,
+		"question_id": result.Question.QuestionID,
+		"post_id":     result.PostID(),
+		"kind":        result.Kind(),
+	}
+
+	converter := md.NewConverter("", true, nil)
+	markdown, err := converter.ConvertString(result.Post.Body)
+	if err != nil {
 		return err
 	}
 
+	link := result.Link()
+	fields := []slack.AttachmentField{
+		{
+			Title: "# Score",
+			Value: strconv.FormatInt(int64(result.Post.Score), 10),
+			Short: true,
+		},
+	}
+
+	if result.Kind() == "answer" {
+		accepted := "✅"
+		if !result.Post.IsAccepted {
+			accepted = "🚫"
+		}
+
+		fields = append(fields, slack.AttachmentField{
+			Title: "Accepted",
+			Value: accepted,
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   fmt.Sprintf("New %s on %s!", result.Kind(), stackexchangeSiteName(result.Site)),
+		AuthorName: result.Post.Owner.DisplayName,
+		AuthorLink: result.Post.Owner.Link,
+		AuthorIcon: result.Post.Owner.ProfileImage,
+		Title:      result.Question.Title,
+		TitleLink:  link,
+		Text:       markdown,
+		MarkdownIn: []string{"text"},
+		Footer:     "Stackoverflow Notification",
+		FooterIcon: stackoverflowIconURL,
+		Ts:         json.Number(strconv.FormatInt(result.Post.CreationDate, 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":stackoverflow:"),
+		slack.MsgOptionText(fmt.Sprintf("New %s on <%s|%s>", result.Kind(), link, stackexchangeSiteName(result.Site)), false),
+		slack.MsgOptionUsername("Stackoverflow Notification"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+// migrateStackoverflowQuestions copies questions tracked before multiple
+// sites were supported, so they are not announced again
+func migrateStackoverflowQuestions(config *Config, db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Question{}) {
+		return nil
+	}
+
+	var count int64
+	if dbResult := db.Model(&StackexchangeQuestion{}).Count(&count); dbResult.Error != nil {
+		return dbResult.Error
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	var questions []Question
+	if dbResult := db.Find(&questions); dbResult.Error != nil {
+		return dbResult.Error
+	}
+
+	site := util.SiteStackOverflow
+	if config.Site != "" {
+		site = config.Site
+	}
+
+	log.WithField("question_count", len(questions)).Info("Migrating questions")
+	for _, question := range questions {
+		entity := StackexchangeQuestion{
+			Site:       site,
+			QuestionID: question.ID,
+			Title:      question.Title,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			return dbResult.Error
+		}
+	}
+
 	return nil
 }
 
 func processStackoverflow(config *Config, db *gorm.DB) error {
-	if err := db.AutoMigrate(&Question{}); err != nil {
-		return fmt.Errorf("error migrating Question: %w", err)
+	if err := db.AutoMigrate(&StackexchangeQuestion{}, &StackexchangePost{}); err != nil {
+		return fmt.Errorf("error migrating StackexchangeQuestion: %w", err)
 	}
 
-	log.Info("Fetching questions")
-	questions, err := getQuestions(config)
-	if err != nil {
-		return err
+	if err := migrateStackoverflowQuestions(config, db); err != nil {
+		return fmt.Errorf("error migrating Question: %w", err)
 	}
 
 	inserted := 0
 	notified := 0
-	log.WithField("question_count", len(questions)).Info("Processing questions")
-	for _, question := range questions {
-		logFields := log.Fields{
-			"question_id": question.QuestionId,
-			"title":       question.Title,
-		}
+	processed := 0
+	for _, site := range stackexchangeSites(config) {
+		log.WithField("site", site).Info("Fetching questions")
+		var questions []stackoverflow.Question
+		for _, tag := range stackexchangeTags(site, config) {
+			tagged, err := getQuestions(site, tag)
+			if err != nil {
+				return err
+			}
 
-		var entity Question
-		result := db.First(&entity, "id = ?", question.QuestionId)
-		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			continue
+			questions = append(questions, tagged...)
 		}
 
-		log.WithFields(logFields).Info("Inserting new question")
-		entity = Question{
-			ID:    question.QuestionId,
-			Title: question.Title,
+		if query, ok := config.StackexchangeQueries[site]; ok {
+			searched, err := searchQuestions(site, query, config)
+			if err != nil {
+				return err
+			}
+
+			questions = append(questions, searched...)
 		}
 
-		if result := db.Create(&entity); result.Error != nil {
-			log.WithError(result.Error).WithFields(logFields).Fatal("error inserting question into database")
-			continue
+		processed += len(questions)
+		log.WithFields(log.Fields{
+			"site":           site,
+			"question_count": len(questions),
+		}).Info("Processing questions")
+		for _, question := range questions {
+			logFields := log.Fields{
+				"site":        site,
+				"question_id": question.QuestionId,
+				"title":       question.Title,
+			}
+
+			item := stackoverflowQuestionFilterItem(question)
+			if !config.Filters.Allow(item) {
+				continue
+			}
+
+			var entity StackexchangeQuestion
+			result := db.First(&entity, "site = ? AND question_id = ?", site, question.QuestionId)
+			if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				continue
+			}
+
+			log.WithFields(logFields).Info("Inserting new question")
+			announcedAt := time.Now()
+			entity = StackexchangeQuestion{
+				Site:        site,
+				QuestionID:  question.QuestionId,
+				Title:       question.Title,
+				Link:        question.Link,
+				AnnouncedAt: &announcedAt,
+				Language:    item.Language,
+			}
+
+			if result := db.Create(&entity); result.Error != nil {
+				log.WithError(result.Error).WithFields(logFields).Fatal("error inserting question into database")
+				continue
+			}
+
+			inserted += 1
+			notifyConfig, err := prepareMention(item, config, db)
+			if err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error recording question")
+				continue
+			}
+			if notifyConfig == nil {
+				continue
+			}
+
+			if err := sendSlackNotificationForStackoverflow(site, question, notifyConfig); err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error posting question to slack")
+				continue
+			}
+
+			if err := recordNotification(notifyConfig, db); err != nil {
+				log.WithError(err).WithFields(logFields).Fatal("error recording notification for question")
+				continue
+			}
+
+			notified += 1
 		}
 
-		inserted += 1
-		if err := sendSlackNotificationForStackoverflow(question, config); err != nil {
-			log.WithError(err).WithFields(logFields).Fatal("error posting question to slack")
-			continue
+		log.WithField("site", site).Info("Fetching answers and comments")
+		posts, err := getFollowedPosts(site, config, db)
+		if err != nil {
+			return err
 		}
 
-		notified += 1
+		processed += len(posts)
+		for _, post := range posts {
+			logFields := log.Fields{
+				"site":    site,
+				"post_id": post.PostID(),
+				"kind":    post.Kind(),
+			}
+
+			item := post.FilterItem()
+			if !config.Filters.Allow(item) {
+				continue
+			}
+
+			var entity StackexchangePost
+			result := db.First(&entity, "site = ? AND post_id = ? AND kind = ?", site, post.PostID(), post.Kind())
+			if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				continue
+			}
+
+			log.WithFields(logFields).Infof("Inserting new %s", post.Kind())
+			entity = StackexchangePost{
+				Site:     site,
+				PostID:   post.PostID(),
+				Kind:     post.Kind(),
+				Language: item.Language,
+			}
+
+			if result := db.Create(&entity); result.Error != nil {
+				log.WithError(result.Error).WithFields(logFields).Fatalf("error inserting %s into database", post.Kind())
+				continue
+			}
+
+			inserted += 1
+			notifyConfig, err := prepareMention(item, config, db)
+			if err != nil {
+				log.WithError(err).WithFields(logFields).Fatalf("error recording %s", post.Kind())
+				continue
+			}
+			if notifyConfig == nil {
+				continue
+			}
+
+			if err := sendSlackNotificationForStackexchangePost(post, notifyConfig); err != nil {
+				log.WithError(err).WithFields(logFields).Fatalf("error posting %s to slack", post.Kind())
+				continue
+			}
+
+			if err := recordNotification(notifyConfig, db); err != nil {
+				log.WithError(err).WithFields(logFields).Fatalf("error recording notification for %s", post.Kind())
+				continue
+			}
+
+			notified += 1
+		}
 	}
 	log.WithFields(log.Fields{
-		"processed_question_count": len(questions),
+		"processed_question_count": processed,
 		"inserted_question_count":  inserted,
 		"notified_question_count":  notified,
 	}).Info("Done with stackoverflow")
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var substackIconURL = "https://substack.com/favicon.ico"
+
+// substackMaxPages caps how many pages are fetched on a single run
+var substackMaxPages = 5
+
+// substackWordsPerMinute is used to estimate reading time, as the search
+// results only include a word count
+var substackWordsPerMinute = 250
+
+type SubstackArticle struct {
+	ID        int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	ArticleID int64  `gorm:"not null" form:"article_id" json:"article_id"`
+	Title     string `gorm:"not null" form:"title" json:"title"`
+	Language  string `form:"language" json:"language"`
+}
+
+type SubstackResponse struct {
+	Results []SubstackArticleResult `json:"results"`
+	More    bool                    `json:"more"`
+}
+
+type SubstackArticleResult struct {
+	ID               int64     `json:"id"`
+	Title            string    `json:"title"`
+	Subtitle         string    `json:"subtitle"`
+	CanonicalURL     string    `json:"canonical_url"`
+	PostDate         time.Time `json:"post_date"`
+	Wordcount        int       `json:"wordcount"`
+	PublishedBylines []struct {
+		Name             string `json:"name"`
+		Handle           string `json:"handle"`
+		PhotoURL         string `json:"photo_url"`
+		PublicationUsers []struct {
+			Publication struct {
+				Name         string `json:"name"`
+				Subdomain    string `json:"subdomain"`
+				CustomDomain string `json:"custom_domain"`
+			} `json:"publication"`
+		} `json:"publicationUsers"`
+	} `json:"publishedBylines"`
+}
+
+// ReadingTimeMinutes estimates the reading time from the word count
+func (r SubstackArticleResult) ReadingTimeMinutes() int {
+	if r.Wordcount == 0 {
+		return 0
+	}
+
+	return (r.Wordcount + substackWordsPerMinute - 1) / substackWordsPerMinute
+}
+
+// Publication returns the name and url of the publication the article was
+// published in, falling back to the host of the article
+func (r SubstackArticleResult) Publication() (string, string) {
+	u, err := url.Parse(r.CanonicalURL)
+	if err != nil {
+		return "", ""
+	}
+
+	publicationURL := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	for _, byline := range r.PublishedBylines {
+		for _, user := range byline.PublicationUsers {
+			publication := user.Publication
+			if publication.CustomDomain == u.Host || fmt.Sprintf("%s.substack.com", publication.Subdomain) == u.Host {
+				return publication.Name, publicationURL
+			}
+		}
+	}
+
+	return u.Host, publicationURL
+}
+
+// FilterItem converts the article for evaluation against the filter rules
+func (r SubstackArticleResult) FilterItem() FilterItem {
+	author := ""
+	if len(r.PublishedBylines) > 0 {
+		author = r.PublishedBylines[0].Handle
+	}
+
+	text := strings.Join([]string{r.Title, r.Subtitle}, " ")
+	return FilterItem{
+		Source:   "substack",
+		Text:     text,
+		Author:   author,
+		Language: detectLanguage(text),
+		URL:      r.CanonicalURL,
+		Links:    []string{r.CanonicalURL},
+		Metrics: map[string]float64{
+			"words": float64(r.Wordcount),
+		},
+	}
+}
+
+func getSubstackArticles(config *Config) ([]SubstackArticleResult, error) {
+	var results []SubstackArticleResult
+	for page := 0; page < substackMaxPages; page++ {
+		log.WithField("page", page+1).Info("Fetching page")
+		var response SubstackResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"query": config.Tag,
+				"page":  strconv.FormatInt(int64(page), 10),
+			}).
+			SetResult(&response).
+			Get("https://substack.com/api/v1/post/search")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error searching substack: %s", resp.Status())
+		}
+
+		for _, article := range response.Results {
+			if !config.Matcher.Verify("substack", strconv.FormatInt(article.ID, 10), article.Title, article.Subtitle) {
+				continue
+			}
+
+			results = append(results, article)
+		}
+
+		if !response.More || len(response.Results) == 0 {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForSubstackArticle(result SubstackArticleResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"article_id": result.ID,
+		"title":      result.Title,
+	}
+
+	fields := []slack.AttachmentField{}
+	if publicationName, publicationURL := result.Publication(); publicationName != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Publication",
+			Value: fmt.Sprintf("<%s|%s>", publicationURL, publicationName),
+			Short: true,
+		})
+	}
+
+	if readingTime := result.ReadingTimeMinutes(); readingTime > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Reading Time",
+			Value: fmt.Sprintf("%d min", readingTime),
+			Short: true,
+		})
+	}
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New article on Substack!",
+		Title:      result.Title,
+		TitleLink:  result.CanonicalURL,
+		Text:       result.Subtitle,
+		Footer:     "Substack Article Notification",
+		FooterIcon: substackIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.PostDate.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	if len(result.PublishedBylines) > 0 {
+		byline := result.PublishedBylines[0]
+		attachment.AuthorName = byline.Name
+		attachment.AuthorIcon = byline.PhotoURL
+		if byline.Handle != "" {
+			attachment.AuthorLink = fmt.Sprintf("https://substack.com/@%s", byline.Handle)
+		}
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":substack:"),
+		slack.MsgOptionText("New article on <"+result.CanonicalURL+"|Substack>", false),
+		slack.MsgOptionUsername("Substack Article Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processSubstackArticles(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&SubstackArticle{}); err != nil {
+		return fmt.Errorf("error migrating SubstackArticle: %w", err)
+	}
+
+	log.Info("Fetching articles")
+	results, err := getSubstackArticles(config)
+	if err != nil {
+		return err
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("article_count", len(results)).Info("Processing articles")
+	for _, result := range results {
+		logFields := log.Fields{
+			"article_id": result.ID,
+			"title":      result.Title,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		var entity SubstackArticle
+		if dbResult := db.First(&entity, "article_id = ?", result.ID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new article")
+		entity = SubstackArticle{
+			ArticleID: result.ID,
+			Title:     result.Title,
+			Language:  item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting article into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording article")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForSubstackArticle(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting article to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for article")
+			continue
+		}
+
+		notified += 1
+	}
+	log.WithFields(log.Fields{
+		"processed_article_count": len(results),
+		"inserted_article_count":  inserted,
+		"notified_article_count":  notified,
+	}).Info("Done with substack articles")
+
+	return nil
+}
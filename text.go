@@ -0,0 +1,16 @@
+package main
+
+import (
+	"strings"
+)
+
+// textExcerpt converts html or plain text to plain text, truncated to the
+// given number of characters, keeping paragraphs on separate lines
+func textExcerpt(text string, length int) string {
+	excerpt := stripFeedHTML(strings.ReplaceAll(text, "<p>", "\n"))
+	if runes := []rune(excerpt); len(runes) > length {
+		excerpt = strings.TrimSpace(string(runes[:length])) + "…"
+	}
+
+	return excerpt
+}
@@ -19,8 +19,9 @@ import (
 var twitterIconURL = "https://emoji.slack-edge.com/T085AJH3L/twitter/290f7fdbde70c82d.png"
 
 type TwitterTweet struct {
-	ID      int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
-	TweetID string `gorm:"not null" form:"tweet_id" json:"tweet_id"`
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	TweetID  string `gorm:"not null" form:"tweet_id" json:"tweet_id"`
+	Language string `form:"language" json:"language"`
 }
 
 type authorize struct {
@@ -56,6 +57,8 @@ func getTweets(config *Config) ([]*twitter.TweetDictionary, error) {
 			twitter.TweetFieldConversationID,
 			twitter.TweetFieldAttachments,
 			twitter.TweetFieldLanguage,
+			twitter.TweetFieldPublicMetrics,
+			twitter.TweetFieldEntities,
 		},
 	}
 
@@ -64,117 +67,97 @@ func getTweets(config *Config) ([]*twitter.TweetDictionary, error) {
 		return results, fmt.Errorf("tweet lookup error: %v", err)
 	}
 
-	// this is rough but many tweets should be ignored in these languages because they refer to either:
-	// - some pop artist's dog (kpop I think)
-	// - count dooku (a mispelling from star wars)
-	// - something crappy (telegu I believe)
-	// ideally we can parse the entities and tell if its actually about dokku,
-	// but honestly I don't care too much
-	ignoreLanguages := map[string]bool{
-		"es": true,
-		"et": true,
-		"ja": true,
-		"in": true,
-		"it": true,
-	}
-
-	// ignore anything with these words too
-	ignoreWords := []string{
-		"caliphate",
-		"chennai",
-		"chatta",
-		"chettha",
-		"comte",
-		"conde",
-		"disney",
-		"dokkan",
-		"hera",
-		"imarat",
-		"isis",
-		"luke",
-		"kadyrov",
-		"movie",
-		"shiseru",
-		"sushi",
-		"tamil",
-		"theatre",
-		"theater",
-		"umarov",
-	}
-
-	// ignore these authors completely
-	ignoreAuthors := []string{"dokku"}
-
-	// allow all tweets with these words to go through
-	allowWords := []string{"caprover", "coolify", "heroku"}
-
 	for _, tweet := range tweetResponse.Raw.TweetDictionaries() {
-		ignore := false
-		for _, word := range allowWords {
-			if strings.Contains(strings.ToLower(tweet.Tweet.Text), word) {
-				results = append(results, tweet)
-				ignore = true
-				break
+		// links in the text are shortened, so check where they lead too
+		values := []string{tweet.Tweet.Text}
+		if tweet.Tweet.Entities != nil {
+			for _, entity := range tweet.Tweet.Entities.URLs {
+				values = append(values, entity.ExpandedURL)
 			}
 		}
 
-		if ignoreLanguages[tweet.Tweet.Language] {
+		if !config.Matcher.Verify("twitter", tweet.Tweet.ID, values...) {
 			continue
 		}
 
-		for _, word := range ignoreWords {
-			if strings.Contains(strings.ToLower(tweet.Tweet.Text), word) {
-				ignore = true
-				break
-			}
-		}
-
-		for _, author := range ignoreAuthors {
-			if tweet.Author.UserName == author {
-				ignore = true
-				break
-			}
-		}
-
-		// ignore anyone with the tag in the name
-		if strings.Contains(strings.ToLower(tweet.Author.UserName), config.Tag) {
+		decision := config.Filters.Evaluate(twitterFilterItem(tweet))
+		if !decision.Allowed {
 			continue
 		}
 
-		// ignore anyone with the tag in the username
-		if strings.Contains(strings.ToLower(tweet.Author.Name), config.Tag) {
+		// posts explicitly allowed by a rule skip the remaining checks
+		if decision.Rule == nil && !isRelevantTweet(tweet, config) {
 			continue
 		}
 
-		for _, mention := range tweet.Mentions {
-			if strings.Contains(strings.ToLower(mention.User.UserName), config.Tag) {
-				ignore = true
-				break
-			}
+		results = append(results, tweet)
+	}
 
-			// ignore anyone with the tag in the username
-			if strings.Contains(strings.ToLower(mention.User.Name), config.Tag) {
-				ignore = true
-				break
-			}
+	return results, nil
+}
+
+// twitterFilterItem converts the tweet for evaluation against the filter rules
+func twitterFilterItem(tweet *twitter.TweetDictionary) FilterItem {
+	item := FilterItem{
+		Source:   "twitter",
+		Text:     tweet.Tweet.Text,
+		Language: itemLanguage(tweet.Tweet.Language, tweet.Tweet.Text),
+		Metrics:  map[string]float64{},
+	}
+
+	if tweet.Author != nil {
+		item.Author = tweet.Author.UserName
+		item.URL = fmt.Sprintf("https://twitter.com/%s/status/%s", tweet.Author.UserName, tweet.Tweet.ID)
+		item.Links = append(item.Links, item.URL)
+	}
+
+	if tweet.Tweet.Entities != nil {
+		for _, entity := range tweet.Tweet.Entities.URLs {
+			item.Links = append(item.Links, entity.ExpandedURL)
 		}
+	}
 
-		// ignore retweets
-		for _, reference := range tweet.ReferencedTweets {
-			if reference.Reference.Type == "retweeted" {
-				ignore = true
-				break
-			}
+	if metrics := tweet.Tweet.PublicMetrics; metrics != nil {
+		item.Metrics["likes"] = float64(metrics.Likes)
+		item.Metrics["replies"] = float64(metrics.Replies)
+		item.Metrics["retweets"] = float64(metrics.Retweets)
+	}
+
+	return item
+}
+
+// isRelevantTweet ignores tweets by or mentioning accounts named after the
+// tag, along with retweets
+func isRelevantTweet(tweet *twitter.TweetDictionary, config *Config) bool {
+	// ignore anyone with the tag in the name
+	if strings.Contains(strings.ToLower(tweet.Author.UserName), config.Tag) {
+		return false
+	}
+
+	// ignore anyone with the tag in the username
+	if strings.Contains(strings.ToLower(tweet.Author.Name), config.Tag) {
+		return false
+	}
+
+	for _, mention := range tweet.Mentions {
+		if strings.Contains(strings.ToLower(mention.User.UserName), config.Tag) {
+			return false
 		}
 
-		if ignore {
-			continue
+		// ignore anyone with the tag in the username
+		if strings.Contains(strings.ToLower(mention.User.Name), config.Tag) {
+			return false
 		}
+	}
 
-		results = append(results, tweet)
+	// ignore retweets
+	for _, reference := range tweet.ReferencedTweets {
+		if reference.Reference.Type == "retweeted" {
+			return false
+		}
 	}
 
-	return results, nil
+	return true
 }
 
 func sendSlackNotificationForTwitterTweet(result *twitter.TweetDictionary, config *Config) error {
@@ -215,12 +198,7 @@ func sendSlackNotificationForTwitterTweet(result *twitter.TweetDictionary, confi
 		slack.MsgOptionDisableLinkUnfurl(),
 	}
 
-	api := slack.New(config.SlackToken)
-	if _, _, err := api.PostMessage(config.SlackChannelID, messageOpts...); err != nil {
-		return err
-	}
-
-	return nil
+	return postSlackMessage(config, messageOpts...)
 }
 
 func processTwitter(config *Config, db *gorm.DB) error {
@@ -253,8 +231,10 @@ func processTwitter(config *Config, db *gorm.DB) error {
 		}
 
 		log.WithFields(logFields).Info("Inserting new tweet")
+		item := twitterFilterItem(result)
 		entity = TwitterTweet{
-			TweetID: result.Tweet.ID,
+			TweetID:  result.Tweet.ID,
+			Language: item.Language,
 		}
 
 		if dbResult := db.Create(&entity); dbResult.Error != nil {
@@ -263,11 +243,25 @@ func processTwitter(config *Config, db *gorm.DB) error {
 		}
 
 		inserted += 1
-		if err := sendSlackNotificationForTwitterTweet(result, config); err != nil {
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording tweet")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForTwitterTweet(result, notifyConfig); err != nil {
 			log.WithError(err).WithFields(logFields).Fatal("error posting tweet to slack")
 			continue
 		}
 
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for tweet")
+			continue
+		}
+
 		notified += 1
 	}
 	log.WithFields(log.Fields{
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"gorm.io/gorm"
+)
+
+var youtubeIconURL = "https://www.youtube.com/favicon.ico"
+
+// youtubeMaxPages caps how many pages are fetched on a single run, as each
+// search request costs 100 units of the daily api quota
+var youtubeMaxPages = 3
+
+// youtubeInitialLookback is how far back the first search looks when there
+// is no stored cursor
+var youtubeInitialLookback = 7 * 24 * time.Hour
+
+var youtubeDurationRegexp = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+type YoutubeVideo struct {
+	ID       int32  `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	VideoID  string `gorm:"not null" form:"video_id" json:"video_id"`
+	Title    string `gorm:"not null" form:"title" json:"title"`
+	Language string `form:"language" json:"language"`
+}
+
+// YoutubeCursor tracks the videos that have been searched through. Videos
+// published after PublishedAfter have not all been seen yet. When a search
+// has more pages than are fetched in a run, PublishedBefore holds the oldest
+// video fetched so that the next run resumes from there, and LatestAt the
+// newest one, which PublishedAfter advances to once the search is finished.
+type YoutubeCursor struct {
+	ID              int32     `gorm:"AUTO_INCREMENT" form:"id" json:"id"`
+	Query           string    `gorm:"not null" form:"query" json:"query"`
+	PublishedAfter  time.Time `gorm:"not null" form:"published_after" json:"published_after"`
+	PublishedBefore time.Time `form:"published_before" json:"published_before"`
+	LatestAt        time.Time `form:"latest_at" json:"latest_at"`
+}
+
+type YoutubeSearchResponse struct {
+	NextPageToken string `json:"nextPageToken"`
+	Items         []struct {
+		ID struct {
+			VideoID string `json:"videoId"`
+		} `json:"id"`
+		Snippet YoutubeSnippet `json:"snippet"`
+	} `json:"items"`
+}
+
+type YoutubeVideosResponse struct {
+	Items []struct {
+		ID      string `json:"id"`
+		Snippet struct {
+			DefaultLanguage      string `json:"defaultLanguage"`
+			DefaultAudioLanguage string `json:"defaultAudioLanguage"`
+		} `json:"snippet"`
+		ContentDetails struct {
+			Duration string `json:"duration"`
+		} `json:"contentDetails"`
+		Statistics struct {
+			ViewCount string `json:"viewCount"`
+		} `json:"statistics"`
+	} `json:"items"`
+}
+
+type YoutubeSnippet struct {
+	PublishedAt  time.Time `json:"publishedAt"`
+	ChannelID    string    `json:"channelId"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	ChannelTitle string    `json:"channelTitle"`
+	Thumbnails   map[string]struct {
+		URL string `json:"url"`
+	} `json:"thumbnails"`
+}
+
+type YoutubeVideoResult struct {
+	VideoID   string
+	Snippet   YoutubeSnippet
+	Duration  time.Duration
+	ViewCount int
+	Language  string
+}
+
+// Link returns the watch url of the video
+func (r YoutubeVideoResult) Link() string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s", r.VideoID)
+}
+
+// FilterItem converts the video for evaluation against the filter rules,
+// where the author is the channel name
+func (r YoutubeVideoResult) FilterItem() FilterItem {
+	text := strings.Join([]string{r.Snippet.Title, r.Snippet.Description}, " ")
+	return FilterItem{
+		Source:   "youtube",
+		Text:     text,
+		Author:   r.Snippet.ChannelTitle,
+		Language: itemLanguage(r.Language, text),
+		URL:      r.Link(),
+		Links:    []string{r.Link()},
+		Metrics: map[string]float64{
+			"views":    float64(r.ViewCount),
+			"duration": r.Duration.Seconds(),
+		},
+	}
+}
+
+// ThumbnailURL returns the largest thumbnail available for the video
+func (r YoutubeVideoResult) ThumbnailURL() string {
+	for _, size := range []string{"high", "medium", "default"} {
+		if thumbnail, ok := r.Snippet.Thumbnails[size]; ok {
+			return thumbnail.URL
+		}
+	}
+
+	return ""
+}
+
+// parseYoutubeDuration parses the iso 8601 durations returned by the api,
+// such as PT1H2M3S
+func parseYoutubeDuration(value string) time.Duration {
+	matches := youtubeDurationRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		return 0
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	duration := time.Duration(0)
+	for i, unit := range units {
+		if matches[i+1] == "" {
+			continue
+		}
+
+		n, _ := strconv.Atoi(matches[i+1])
+		duration += time.Duration(n) * unit
+	}
+
+	return duration
+}
+
+// formatYoutubeDuration formats a duration like the youtube player does
+func formatYoutubeDuration(duration time.Duration) string {
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	seconds := int(duration.Seconds()) % 60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
+
+// getYoutubeVideos searches for videos published within the range, newest
+// first. When there are more pages than are fetched, the publish time of the
+// oldest video fetched is returned to resume from, and otherwise zero.
+func getYoutubeVideos(publishedAfter time.Time, publishedBefore time.Time, config *Config) ([]YoutubeVideoResult, time.Time, error) {
+	var results []YoutubeVideoResult
+	var oldest time.Time
+	pageToken := ""
+	for page := 1; page <= youtubeMaxPages; page++ {
+		log.WithField("page", page).Info("Fetching page")
+		params := map[string]string{
+			"part":           "snippet",
+			"q":              config.Tag,
+			"type":           "video",
+			"order":          "date",
+			"maxResults":     "50",
+			"publishedAfter": publishedAfter.UTC().Format(time.RFC3339),
+			"key":            config.YoutubeApiKey,
+		}
+		if !publishedBefore.IsZero() {
+			params["publishedBefore"] = publishedBefore.UTC().Format(time.RFC3339)
+		}
+		if pageToken != "" {
+			params["pageToken"] = pageToken
+		}
+
+		var response YoutubeSearchResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(params).
+			SetResult(&response).
+			Get("https://www.googleapis.com/youtube/v3/search")
+		if err != nil {
+			return results, oldest, err
+		}
+
+		if resp.IsError() {
+			return results, oldest, fmt.Errorf("error searching youtube: %s", resp.Status())
+		}
+
+		for _, item := range response.Items {
+			if item.ID.VideoID == "" {
+				continue
+			}
+
+			if oldest.IsZero() || item.Snippet.PublishedAt.Before(oldest) {
+				oldest = item.Snippet.PublishedAt
+			}
+
+			// the search api html-escapes titles and descriptions
+			snippet := item.Snippet
+			snippet.Title = html.UnescapeString(snippet.Title)
+			snippet.Description = html.UnescapeString(snippet.Description)
+			if !config.Matcher.Verify("youtube", item.ID.VideoID, snippet.Title, snippet.Description, snippet.ChannelTitle) {
+				continue
+			}
+
+			results = append(results, YoutubeVideoResult{
+				VideoID: item.ID.VideoID,
+				Snippet: snippet,
+			})
+		}
+
+		if response.NextPageToken == "" {
+			return results, time.Time{}, nil
+		}
+		pageToken = response.NextPageToken
+	}
+
+	return results, oldest, nil
+}
+
+// getYoutubeVideoDetails sets the duration, view count and language of each
+// video, as the search results only include part of the snippet
+func getYoutubeVideoDetails(results []YoutubeVideoResult, config *Config) ([]YoutubeVideoResult, error) {
+	index := map[string]int{}
+	for i, result := range results {
+		index[result.VideoID] = i
+	}
+
+	for start := 0; start < len(results); start += 50 {
+		end := min(start+50, len(results))
+		ids := []string{}
+		for _, result := range results[start:end] {
+			ids = append(ids, result.VideoID)
+		}
+
+		var response YoutubeVideosResponse
+		client := resty.New()
+		resp, err := client.R().
+			SetQueryParams(map[string]string{
+				"part": "snippet,contentDetails,statistics",
+				"id":   strings.Join(ids, ","),
+				"key":  config.YoutubeApiKey,
+			}).
+			SetResult(&response).
+			Get("https://www.googleapis.com/youtube/v3/videos")
+		if err != nil {
+			return results, err
+		}
+
+		if resp.IsError() {
+			return results, fmt.Errorf("error fetching youtube video details: %s", resp.Status())
+		}
+
+		for _, item := range response.Items {
+			i, ok := index[item.ID]
+			if !ok {
+				continue
+			}
+
+			results[i].Duration = parseYoutubeDuration(item.ContentDetails.Duration)
+			results[i].ViewCount, _ = strconv.Atoi(item.Statistics.ViewCount)
+
+			// the spoken language is more telling than that of the title
+			results[i].Language = item.Snippet.DefaultAudioLanguage
+			if results[i].Language == "" {
+				results[i].Language = item.Snippet.DefaultLanguage
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func sendSlackNotificationForYoutubeVideo(result YoutubeVideoResult, config *Config) error {
+	if !config.NotifySlack {
+		return nil
+	}
+
+	logFields := log.Fields{
+		"video_id": result.VideoID,
+		"title":    result.Snippet.Title,
+	}
+
+	fields := []slack.AttachmentField{}
+	if result.Duration > 0 {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Duration",
+			Value: formatYoutubeDuration(result.Duration),
+			Short: true,
+		})
+	}
+
+	fields = append(fields, slack.AttachmentField{
+		Title: "# Views",
+		Value: strconv.FormatInt(int64(result.ViewCount), 10),
+		Short: true,
+	})
+
+	attachment := slack.Attachment{
+		Color:      "#36a64f",
+		Fallback:   "New video on YouTube!",
+		AuthorName: result.Snippet.ChannelTitle,
+		AuthorLink: fmt.Sprintf("https://www.youtube.com/channel/%s", result.Snippet.ChannelID),
+		Title:      result.Snippet.Title,
+		TitleLink:  result.Link(),
+		Text:       result.Snippet.Description,
+		ThumbURL:   result.ThumbnailURL(),
+		Footer:     "YouTube Video Notification",
+		FooterIcon: youtubeIconURL,
+		Ts:         json.Number(strconv.FormatInt(int64(result.Snippet.PublishedAt.Unix()), 10)),
+		Fields:     fields,
+	}
+
+	log.WithFields(logFields).Info("Notifying slack")
+	messageOpts := []slack.MsgOption{
+		slack.MsgOptionAsUser(false),
+		slack.MsgOptionAttachments(attachment),
+		slack.MsgOptionIconEmoji(":youtube:"),
+		slack.MsgOptionText("New video on <"+result.Link()+"|YouTube>", false),
+		slack.MsgOptionUsername("YouTube Video Notifications"),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	return postSlackMessage(config, messageOpts...)
+}
+
+func processYoutube(config *Config, db *gorm.DB) error {
+	if err := db.AutoMigrate(&YoutubeVideo{}, &YoutubeCursor{}); err != nil {
+		return fmt.Errorf("error migrating YoutubeVideo: %w", err)
+	}
+
+	if config.YoutubeApiKey == "" {
+		log.Warn("No YOUTUBE_API_KEY specified, skipping youtube")
+		return nil
+	}
+
+	var cursor YoutubeCursor
+	if dbResult := db.First(&cursor, "query = ?", config.Tag); dbResult.Error != nil && !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error fetching cursor for %s: %w", config.Tag, dbResult.Error)
+	}
+
+	publishedAfter := cursor.PublishedAfter
+	if publishedAfter.IsZero() {
+		publishedAfter = time.Now().Add(-youtubeInitialLookback)
+	}
+
+	log.WithFields(log.Fields{
+		"published_after":  publishedAfter,
+		"published_before": cursor.PublishedBefore,
+	}).Info("Fetching videos")
+	results, resumeBefore, err := getYoutubeVideos(publishedAfter, cursor.PublishedBefore, config)
+	if err != nil {
+		return err
+	}
+
+	var newResults []YoutubeVideoResult
+	for _, result := range results {
+		var entity YoutubeVideo
+		if dbResult := db.First(&entity, "video_id = ?", result.VideoID); !errors.Is(dbResult.Error, gorm.ErrRecordNotFound) {
+			continue
+		}
+
+		newResults = append(newResults, result)
+	}
+
+	// only look up details for videos that will be announced, to save quota
+	newResults, err = getYoutubeVideoDetails(newResults, config)
+	if err != nil {
+		return err
+	}
+
+	inserted := 0
+	notified := 0
+	log.WithField("video_count", len(newResults)).Info("Processing videos")
+	for _, result := range newResults {
+		logFields := log.Fields{
+			"video_id": result.VideoID,
+			"title":    result.Snippet.Title,
+		}
+
+		item := result.FilterItem()
+		if !config.Filters.Allow(item) {
+			continue
+		}
+
+		log.WithFields(logFields).Info("Inserting new video")
+		entity := YoutubeVideo{
+			VideoID:  result.VideoID,
+			Title:    result.Snippet.Title,
+			Language: item.Language,
+		}
+
+		if dbResult := db.Create(&entity); dbResult.Error != nil {
+			log.WithError(dbResult.Error).WithFields(logFields).Fatal("error inserting video into database")
+			continue
+		}
+
+		inserted += 1
+		notifyConfig, err := prepareMention(item, config, db)
+		if err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording video")
+			continue
+		}
+		if notifyConfig == nil {
+			continue
+		}
+
+		if err := sendSlackNotificationForYoutubeVideo(result, notifyConfig); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error posting video to slack")
+			continue
+		}
+
+		if err := recordNotification(notifyConfig, db); err != nil {
+			log.WithError(err).WithFields(logFields).Fatal("error recording notification for video")
+			continue
+		}
+
+		notified += 1
+	}
+
+	// the cursor only moves past videos that were not fetched once the
+	// search has been paged through to the end
+	cursor.Query = config.Tag
+	if cursor.LatestAt.Before(publishedAfter) {
+		cursor.LatestAt = publishedAfter
+	}
+	for _, result := range results {
+		if result.Snippet.PublishedAt.After(cursor.LatestAt) {
+			cursor.LatestAt = result.Snippet.PublishedAt
+		}
+	}
+
+	if resumeBefore.IsZero() {
+		cursor.PublishedAfter = cursor.LatestAt
+	} else {
+		cursor.PublishedAfter = publishedAfter
+		log.WithField("published_before", resumeBefore).Info("More videos than fetched, resuming from the oldest fetched video next run")
+	}
+	cursor.PublishedBefore = resumeBefore
+
+	if dbResult := db.Save(&cursor); dbResult.Error != nil {
+		return fmt.Errorf("error saving cursor for %s: %w", config.Tag, dbResult.Error)
+	}
+
+	log.WithFields(log.Fields{
+		"processed_video_count": len(results),
+		"inserted_video_count":  inserted,
+		"notified_video_count":  notified,
+	}).Info("Done with youtube videos")
+
+	return nil
+}